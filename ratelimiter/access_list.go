@@ -0,0 +1,87 @@
+package ratelimiter
+
+import (
+	"net/netip"
+	"strings"
+)
+
+const AccessDefault = ""
+const AccessAllowed = "allowed"
+const AccessDenied = "denied"
+
+type accessLists struct {
+	allowedIPs    []netip.Prefix
+	deniedIPs     []netip.Prefix
+	allowedTokens map[string]bool
+	deniedTokens  map[string]bool
+}
+
+// GetAccess checks ip and token against the allow and deny lists. A denied match wins over an allowed one.
+func (c *LimiterConfig) GetAccess(ip string, token string) string {
+	lists := c.accessLists
+	if lists == nil {
+		lists = parseAccessLists(c)
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	validIP := err == nil
+	if validIP {
+		addr = addr.Unmap().WithZone("")
+	}
+
+	if (validIP && containsIP(lists.deniedIPs, addr)) || (token != "" && lists.deniedTokens[token]) {
+		return AccessDenied
+	}
+	if (validIP && containsIP(lists.allowedIPs, addr)) || (token != "" && lists.allowedTokens[token]) {
+		return AccessAllowed
+	}
+	return AccessDefault
+}
+
+func parseAccessLists(config *LimiterConfig) *accessLists {
+	return &accessLists{
+		allowedIPs:    parseAccessListIPs(config, config.AllowedIPs),
+		deniedIPs:     parseAccessListIPs(config, config.DeniedIPs),
+		allowedTokens: parseAccessListTokens(config.AllowedTokens),
+		deniedTokens:  parseAccessListTokens(config.DeniedTokens),
+	}
+}
+
+// parseAccessListIPs skips invalid entries, so one typo does not disable the whole list.
+func parseAccessListIPs(config *LimiterConfig, values []string) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		prefix, err := parseIPPrefix(value)
+		if err != nil {
+			PrintfWD(config, "ignoring access list entry \"%s\": %s", value, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+func parseAccessListTokens(values []string) map[string]bool {
+	tokens := map[string]bool{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			tokens[value] = true
+		}
+	}
+	return tokens
+}
+
+func containsIP(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package ratelimiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AccessListTestSuite struct {
+	suite.Suite
+}
+
+func TestAccessListTestSuite(t *testing.T) {
+	suite.Run(t, new(AccessListTestSuite))
+}
+
+func (s *AccessListTestSuite) TestGetAccess() {
+	config := &LimiterConfig{
+		AllowedIPs:    []string{"10.0.0.0/8", "2001:db8::/32"},
+		DeniedIPs:     []string{"10.6.6.6", "invalid"},
+		AllowedTokens: []string{"health"},
+		DeniedTokens:  []string{"revoked"},
+	}
+
+	assert.Equal(s.T(), AccessAllowed, config.GetAccess("10.1.2.3", ""))
+	assert.Equal(s.T(), AccessAllowed, config.GetAccess("::ffff:10.1.2.3", ""))
+	assert.Equal(s.T(), AccessAllowed, config.GetAccess("2001:db8::1", ""))
+	assert.Equal(s.T(), AccessAllowed, config.GetAccess("192.0.2.1", "health"))
+	assert.Equal(s.T(), AccessDenied, config.GetAccess("10.6.6.6", ""))
+	assert.Equal(s.T(), AccessDenied, config.GetAccess("10.1.2.3", "revoked"))
+	assert.Equal(s.T(), AccessDenied, config.GetAccess("10.6.6.6", "health"))
+	assert.Equal(s.T(), AccessDefault, config.GetAccess("192.0.2.1", "other"))
+	assert.Equal(s.T(), AccessDefault, config.GetAccess("", ""))
+}
+
+func (s *AccessListTestSuite) TestGetAccess_Empty() {
+	assert.Equal(s.T(), AccessDefault, (&LimiterConfig{}).GetAccess("10.1.2.3", "abc"))
+}
@@ -0,0 +1,151 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+)
+
+func CheckWithStorageAdapter(ctx context.Context, adapter RateLimitStorageAdapter, keyType string, key string, request *CheckRequest) (*CheckResult, error) {
+	block, err := adapter.GetBlock(ctx, keyType, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if block != nil {
+		return &CheckResult{AlreadyBlocked: true, BlockedUntil: block, ExceededIndex: -1}, nil
+	}
+
+	result, err := incrementWithStorageAdapter(ctx, adapter, keyType, key, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Success {
+		result.BlockedUntil, err = adapter.AddBlock(ctx, keyType, key, request.BlockTimeMilliseconds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// CheckBatchWithStorageAdapter runs the checks one by one, for adapters without a batch of their own.
+func CheckBatchWithStorageAdapter(ctx context.Context, adapter RateLimitStorageAdapter, requests []*BatchCheckRequest) ([]*CheckResult, error) {
+	results := []*CheckResult{}
+	for _, request := range requests {
+		var result *CheckResult
+		var err error
+		if checkAdapter, ok := adapter.(RateLimitCheckStorageAdapter); ok {
+			result, err = checkAdapter.Check(ctx, request.KeyType, request.Key, request.Request)
+		} else {
+			result, err = CheckWithStorageAdapter(ctx, adapter, request.KeyType, request.Key, request.Request)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// CheckSupport returns the error a check with request would fail with on every call, as the adapter lacks the algorithm.
+// Adapters with a Check or CheckBatch of their own handle every request themselves, so they are not checked.
+func CheckSupport(adapter RateLimitStorageAdapter, request *CheckRequest) error {
+	if _, ok := adapter.(RateLimitCheckStorageAdapter); ok {
+		return nil
+	}
+	if _, ok := adapter.(RateLimitBatchCheckStorageAdapter); ok {
+		return nil
+	}
+
+	supported := false
+	switch request.Algorithm {
+	case AlgorithmSlidingLog:
+		if len(request.Limits) > 1 {
+			_, supported = adapter.(RateLimitMultiWindowStorageAdapter)
+			break
+		}
+		if _, ok := adapter.(RateLimitWindowStorageAdapter); !ok && request.Limits[0].WindowMilliseconds != DefaultWindowMilliseconds {
+			return fmt.Errorf("%w: \"%s\" with a window of %dms", ErrAlgorithmNotSupported, AlgorithmSlidingLog, request.Limits[0].WindowMilliseconds)
+		}
+		supported = true
+	case AlgorithmFixedWindow:
+		_, supported = adapter.(RateLimitFixedWindowStorageAdapter)
+	case AlgorithmSlidingWindowCounter:
+		_, supported = adapter.(RateLimitSlidingWindowCounterStorageAdapter)
+	case AlgorithmTokenBucket:
+		_, supported = adapter.(RateLimitTokenBucketStorageAdapter)
+	case AlgorithmGCRA:
+		_, supported = adapter.(RateLimitGCRAStorageAdapter)
+	default:
+		return fmt.Errorf("unknown rate limiter algorithm \"%s\"", request.Algorithm)
+	}
+
+	if !supported {
+		return fmt.Errorf("%w: \"%s\"", ErrAlgorithmNotSupported, request.Algorithm)
+	}
+	return nil
+}
+
+func incrementWithStorageAdapter(ctx context.Context, adapter RateLimitStorageAdapter, keyType string, key string, request *CheckRequest) (*CheckResult, error) {
+	switch request.Algorithm {
+	case AlgorithmSlidingLog:
+		if len(request.Limits) == 1 {
+			return incrementAccessesWithStorageAdapter(ctx, adapter, keyType, key, request.Limits[0])
+		}
+		if windowsAdapter, ok := adapter.(RateLimitMultiWindowStorageAdapter); ok {
+			return newWindowsCheckResult(windowsAdapter.IncrementAccessesInWindows(ctx, keyType, key, request.Limits))
+		}
+	case AlgorithmFixedWindow:
+		if fixedWindowAdapter, ok := adapter.(RateLimitFixedWindowStorageAdapter); ok {
+			return newWindowsCheckResult(fixedWindowAdapter.IncrementFixedWindows(ctx, keyType, key, request.Limits))
+		}
+	case AlgorithmSlidingWindowCounter:
+		if counterAdapter, ok := adapter.(RateLimitSlidingWindowCounterStorageAdapter); ok {
+			return newWindowsCheckResult(counterAdapter.IncrementSlidingWindowCounters(ctx, keyType, key, request.Limits))
+		}
+	case AlgorithmTokenBucket:
+		if tokenBucketAdapter, ok := adapter.(RateLimitTokenBucketStorageAdapter); ok {
+			success, remaining, err := tokenBucketAdapter.TakeToken(ctx, keyType, key, request.BucketCapacity, request.RefillRatePerSecond)
+			return newSingleCheckResult(success, 0, remaining), err
+		}
+	case AlgorithmGCRA:
+		if gcraAdapter, ok := adapter.(RateLimitGCRAStorageAdapter); ok {
+			success, remaining, err := gcraAdapter.CheckCellRate(ctx, keyType, key, request.Limits[0].MaxAccesses, request.Limits[0].WindowMilliseconds, request.BucketCapacity)
+			return newSingleCheckResult(success, 0, remaining), err
+		}
+	default:
+		return nil, fmt.Errorf("unknown rate limiter algorithm \"%s\"", request.Algorithm)
+	}
+
+	return nil, fmt.Errorf("%w: \"%s\"", ErrAlgorithmNotSupported, request.Algorithm)
+}
+
+// incrementAccessesWithStorageAdapter falls back to IncrementAccesses, which only knows the default window.
+func incrementAccessesWithStorageAdapter(ctx context.Context, adapter RateLimitStorageAdapter, keyType string, key string, limit *WindowLimit) (*CheckResult, error) {
+	if windowAdapter, ok := adapter.(RateLimitWindowStorageAdapter); ok {
+		success, count, err := windowAdapter.IncrementAccessesInWindow(ctx, keyType, key, limit.MaxAccesses, limit.WindowMilliseconds)
+		return newSingleCheckResult(success, count, 0), err
+	}
+	if limit.WindowMilliseconds != DefaultWindowMilliseconds {
+		return nil, fmt.Errorf("%w: \"%s\" with a window of %dms", ErrAlgorithmNotSupported, AlgorithmSlidingLog, limit.WindowMilliseconds)
+	}
+	success, count, err := adapter.IncrementAccesses(ctx, keyType, key, limit.MaxAccesses)
+	return newSingleCheckResult(success, count, 0), err
+}
+
+func newSingleCheckResult(success bool, count int64, remaining int64) *CheckResult {
+	result := &CheckResult{Success: success, ExceededIndex: -1, Counts: []int64{count}, Remaining: remaining}
+	if !success {
+		result.ExceededIndex = 0
+	}
+	return result
+}
+
+func newWindowsCheckResult(result *WindowsResult, err error) (*CheckResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &CheckResult{Success: result.Success, ExceededIndex: result.ExceededIndex, Counts: result.Counts}, nil
+}
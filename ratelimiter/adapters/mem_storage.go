@@ -8,21 +8,60 @@ import (
 
 type RateLimitMemoryStorageAdapter struct {
 	mutexAccesses sync.Mutex
+	mutexCounters sync.Mutex
+	mutexBuckets  sync.Mutex
+	mutexCells    sync.Mutex
 	mutexBlocks   sync.Mutex
 	accesses      map[string]*map[string]*[]*time.Time
+	counters      map[string]*map[string]*map[int64]*windowCounter
+	slidingCounts map[string]*map[string]*map[int64]*windowCounter
+	buckets       map[string]*map[string]*tokenBucket
+	arrivals      map[string]*map[string]*time.Time
 	blocks        map[string]*map[string]*time.Time
+	now           func() time.Time
+}
+
+type windowCounter struct {
+	index    int64
+	count    int64
+	previous int64
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
 }
 
 func NewRateLimitMemoryStorageAdapter() *RateLimitMemoryStorageAdapter {
 	adapter := RateLimitMemoryStorageAdapter{}
 	adapter.mutexAccesses = sync.Mutex{}
+	adapter.mutexCounters = sync.Mutex{}
+	adapter.mutexBuckets = sync.Mutex{}
+	adapter.mutexCells = sync.Mutex{}
 	adapter.mutexBlocks = sync.Mutex{}
 	adapter.accesses = map[string]*map[string]*[]*time.Time{}
+	adapter.counters = map[string]*map[string]*map[int64]*windowCounter{}
+	adapter.slidingCounts = map[string]*map[string]*map[int64]*windowCounter{}
+	adapter.buckets = map[string]*map[string]*tokenBucket{}
+	adapter.arrivals = map[string]*map[string]*time.Time{}
 	adapter.blocks = map[string]*map[string]*time.Time{}
+	adapter.now = time.Now
 	return &adapter
 }
 
+func (s *RateLimitMemoryStorageAdapter) Check(ctx context.Context, keyType string, key string, request *CheckRequest) (*CheckResult, error) {
+	return CheckWithStorageAdapter(ctx, s, keyType, key, request)
+}
+
+func (s *RateLimitMemoryStorageAdapter) CheckBatch(ctx context.Context, requests []*BatchCheckRequest) ([]*CheckResult, error) {
+	return CheckBatchWithStorageAdapter(ctx, s, requests)
+}
+
 func (s *RateLimitMemoryStorageAdapter) IncrementAccesses(ctx context.Context, keyType string, key string, maxAccesses int64) (bool, int64, error) {
+	return s.IncrementAccessesInWindow(ctx, keyType, key, maxAccesses, DefaultWindowMilliseconds)
+}
+
+func (s *RateLimitMemoryStorageAdapter) IncrementAccessesInWindow(ctx context.Context, keyType string, key string, maxAccesses int64, windowMilliseconds int64) (bool, int64, error) {
 	s.mutexAccesses.Lock()
 	defer s.mutexAccesses.Unlock()
 
@@ -38,25 +77,77 @@ func (s *RateLimitMemoryStorageAdapter) IncrementAccesses(ctx context.Context, k
 		(*keyTypeData)[key] = keyData
 	}
 
-	filteredKeyData, count := s.filterInLastSecond(keyData)
+	filteredKeyData, count := s.filterInWindow(keyData, time.Duration(windowMilliseconds)*time.Millisecond)
 
 	if count >= maxAccesses {
 		return false, count, nil
 	}
 
-	now := time.Now()
+	now := s.now()
 	updatedKeyData := append(*filteredKeyData, &now)
 	(*keyTypeData)[key] = &updatedKeyData
 
 	return true, count + 1, nil
 }
 
-func (s *RateLimitMemoryStorageAdapter) filterInLastSecond(keyData *[]*time.Time) (*[]*time.Time, int64) {
-	now := time.Now()
+func (s *RateLimitMemoryStorageAdapter) IncrementAccessesInWindows(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error) {
+	s.mutexAccesses.Lock()
+	defer s.mutexAccesses.Unlock()
+
+	keyTypeData, ok := s.accesses[keyType]
+	if !ok {
+		keyTypeData = &map[string]*[]*time.Time{}
+		s.accesses[keyType] = keyTypeData
+	}
+
+	keyData, ok := (*keyTypeData)[key]
+	if !ok {
+		keyData = &[]*time.Time{}
+		(*keyTypeData)[key] = keyData
+	}
+
+	longestWindow := time.Duration(0)
+	for _, limit := range limits {
+		window := time.Duration(limit.WindowMilliseconds) * time.Millisecond
+		if window > longestWindow {
+			longestWindow = window
+		}
+	}
+
+	filteredKeyData, _ := s.filterInWindow(keyData, longestWindow)
+	(*keyTypeData)[key] = filteredKeyData
+
+	result := &WindowsResult{Success: true, ExceededIndex: -1, Counts: make([]int64, len(limits))}
+	for i, limit := range limits {
+		_, count := s.filterInWindow(filteredKeyData, time.Duration(limit.WindowMilliseconds)*time.Millisecond)
+		result.Counts[i] = count
+		if result.Success && count >= limit.MaxAccesses {
+			result.Success = false
+			result.ExceededIndex = i
+		}
+	}
+
+	if !result.Success {
+		return result, nil
+	}
+
+	now := s.now()
+	updatedKeyData := append(*filteredKeyData, &now)
+	(*keyTypeData)[key] = &updatedKeyData
+
+	for i := range result.Counts {
+		result.Counts[i]++
+	}
+
+	return result, nil
+}
+
+func (s *RateLimitMemoryStorageAdapter) filterInWindow(keyData *[]*time.Time, window time.Duration) (*[]*time.Time, int64) {
+	now := s.now()
 	filtered := []*time.Time{}
 
 	for _, value := range *keyData {
-		if now.Sub(*value).Seconds() < 1 {
+		if now.Sub(*value) < window {
 			filtered = append(filtered, value)
 		}
 	}
@@ -64,6 +155,176 @@ func (s *RateLimitMemoryStorageAdapter) filterInLastSecond(keyData *[]*time.Time
 	return &filtered, int64(len(filtered))
 }
 
+func (s *RateLimitMemoryStorageAdapter) IncrementFixedWindows(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error) {
+	s.mutexCounters.Lock()
+	defer s.mutexCounters.Unlock()
+
+	keyTypeData, ok := s.counters[keyType]
+	if !ok {
+		keyTypeData = &map[string]*map[int64]*windowCounter{}
+		s.counters[keyType] = keyTypeData
+	}
+
+	keyData, ok := (*keyTypeData)[key]
+	if !ok {
+		keyData = &map[int64]*windowCounter{}
+		(*keyTypeData)[key] = keyData
+	}
+
+	now := s.now().UnixMilli()
+
+	result := &WindowsResult{Success: true, ExceededIndex: -1, Counts: make([]int64, len(limits))}
+	for i, limit := range limits {
+		index := now / limit.WindowMilliseconds
+
+		counter, ok := (*keyData)[limit.WindowMilliseconds]
+		if !ok || counter.index != index {
+			counter = &windowCounter{index: index}
+			(*keyData)[limit.WindowMilliseconds] = counter
+		}
+
+		counter.count++
+		result.Counts[i] = counter.count
+		if result.Success && counter.count > limit.MaxAccesses {
+			result.Success = false
+			result.ExceededIndex = i
+		}
+	}
+
+	return result, nil
+}
+
+func (s *RateLimitMemoryStorageAdapter) IncrementSlidingWindowCounters(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error) {
+	s.mutexCounters.Lock()
+	defer s.mutexCounters.Unlock()
+
+	keyTypeData, ok := s.slidingCounts[keyType]
+	if !ok {
+		keyTypeData = &map[string]*map[int64]*windowCounter{}
+		s.slidingCounts[keyType] = keyTypeData
+	}
+
+	keyData, ok := (*keyTypeData)[key]
+	if !ok {
+		keyData = &map[int64]*windowCounter{}
+		(*keyTypeData)[key] = keyData
+	}
+
+	now := s.now().UnixMilli()
+
+	counters := make([]*windowCounter, len(limits))
+	result := &WindowsResult{Success: true, ExceededIndex: -1, Counts: make([]int64, len(limits))}
+	for i, limit := range limits {
+		index := now / limit.WindowMilliseconds
+
+		counter, ok := (*keyData)[limit.WindowMilliseconds]
+		if !ok || counter.index < index-1 {
+			counter = &windowCounter{index: index}
+			(*keyData)[limit.WindowMilliseconds] = counter
+		} else if counter.index == index-1 {
+			counter.index = index
+			counter.previous = counter.count
+			counter.count = 0
+		}
+		counters[i] = counter
+
+		elapsed := float64(now-index*limit.WindowMilliseconds) / float64(limit.WindowMilliseconds)
+		estimated := int64(float64(counter.previous)*(1-elapsed)) + counter.count
+		result.Counts[i] = estimated
+		if result.Success && estimated >= limit.MaxAccesses {
+			result.Success = false
+			result.ExceededIndex = i
+		}
+	}
+
+	if !result.Success {
+		return result, nil
+	}
+
+	for i, counter := range counters {
+		counter.count++
+		result.Counts[i]++
+	}
+
+	return result, nil
+}
+
+func (s *RateLimitMemoryStorageAdapter) TakeToken(ctx context.Context, keyType string, key string, capacity int64, refillRatePerSecond int64) (bool, int64, error) {
+	s.mutexBuckets.Lock()
+	defer s.mutexBuckets.Unlock()
+
+	keyTypeData, ok := s.buckets[keyType]
+	if !ok {
+		keyTypeData = &map[string]*tokenBucket{}
+		s.buckets[keyType] = keyTypeData
+	}
+
+	now := s.now()
+
+	bucket, ok := (*keyTypeData)[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(capacity), updatedAt: now}
+		(*keyTypeData)[key] = bucket
+	}
+
+	s.refillBucket(bucket, now, capacity, refillRatePerSecond)
+
+	if bucket.tokens < 1 {
+		return false, 0, nil
+	}
+
+	bucket.tokens--
+
+	return true, int64(bucket.tokens), nil
+}
+
+func (s *RateLimitMemoryStorageAdapter) refillBucket(bucket *tokenBucket, now time.Time, capacity int64, refillRatePerSecond int64) {
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	if elapsed > 0 {
+		bucket.tokens += elapsed * float64(refillRatePerSecond)
+	}
+	if bucket.tokens > float64(capacity) {
+		bucket.tokens = float64(capacity)
+	}
+	bucket.updatedAt = now
+}
+
+func (s *RateLimitMemoryStorageAdapter) CheckCellRate(ctx context.Context, keyType string, key string, maxRequests int64, windowMilliseconds int64, burst int64) (bool, int64, error) {
+	if maxRequests <= 0 {
+		return false, 0, nil
+	}
+
+	s.mutexCells.Lock()
+	defer s.mutexCells.Unlock()
+
+	keyTypeData, ok := s.arrivals[keyType]
+	if !ok {
+		keyTypeData = &map[string]*time.Time{}
+		s.arrivals[keyType] = keyTypeData
+	}
+
+	now := s.now()
+	emissionInterval := time.Duration(windowMilliseconds) * time.Millisecond / time.Duration(maxRequests)
+	burstTolerance := emissionInterval * time.Duration(burst)
+
+	theoreticalArrival := now
+	storedArrival, ok := (*keyTypeData)[key]
+	if ok && storedArrival.After(now) {
+		theoreticalArrival = *storedArrival
+	}
+
+	newTheoreticalArrival := theoreticalArrival.Add(emissionInterval)
+	allowAt := newTheoreticalArrival.Add(-burstTolerance)
+
+	if now.Before(allowAt) {
+		return false, 0, nil
+	}
+
+	(*keyTypeData)[key] = &newTheoreticalArrival
+
+	return true, int64(now.Sub(allowAt) / emissionInterval), nil
+}
+
 func (s *RateLimitMemoryStorageAdapter) GetBlock(ctx context.Context, keyType string, key string) (*time.Time, error) {
 	s.mutexBlocks.Lock()
 	defer s.mutexBlocks.Unlock()
@@ -78,7 +339,7 @@ func (s *RateLimitMemoryStorageAdapter) GetBlock(ctx context.Context, keyType st
 		return nil, nil
 	}
 
-	if blockedUntil.After(time.Now()) {
+	if blockedUntil.After(s.now()) {
 		return blockedUntil, nil
 	}
 
@@ -96,7 +357,7 @@ func (s *RateLimitMemoryStorageAdapter) AddBlock(ctx context.Context, keyType st
 		s.blocks[keyType] = keyTypeData
 	}
 
-	blockedUntil := time.Now().Add(time.Duration(int64(time.Millisecond) * milliseconds))
+	blockedUntil := s.now().Add(time.Duration(int64(time.Millisecond) * milliseconds))
 	(*keyTypeData)[key] = &blockedUntil
 
 	return &blockedUntil, nil
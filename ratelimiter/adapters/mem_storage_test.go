@@ -3,6 +3,7 @@ package adapters
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -51,6 +52,408 @@ func (s *RateLimitMemoryStorageAdapterTestSuite) TestIncrementAccesses() {
 	}
 }
 
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestIncrementAccesses_Window() {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	now := time.Now()
+	storageAdapter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		success, _, _ := storageAdapter.IncrementAccessesInWindow(ctx, keyType, keyValue, 2, 60000)
+		assert.True(s.T(), success)
+	}
+
+	now = now.Add(time.Second * 30)
+	success, count, err := storageAdapter.IncrementAccessesInWindow(ctx, keyType, keyValue, 2, 60000)
+	assert.False(s.T(), success)
+	assert.Equal(s.T(), int64(2), count)
+	assert.Nil(s.T(), err)
+
+	now = now.Add(time.Second * 30)
+	success, count, err = storageAdapter.IncrementAccessesInWindow(ctx, keyType, keyValue, 2, 60000)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(1), count)
+	assert.Nil(s.T(), err)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestIncrementAccessesInWindows() {
+	ctx := s.context
+	keyType := "TOKEN"
+	keyValue := "abc"
+	limits := []*WindowLimit{
+		{MaxAccesses: 2, WindowMilliseconds: 1000},
+		{MaxAccesses: 3, WindowMilliseconds: 60000},
+	}
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	now := time.Now()
+	storageAdapter.now = func() time.Time { return now }
+
+	result, err := storageAdapter.IncrementAccessesInWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1, 1}}, result)
+
+	result, err = storageAdapter.IncrementAccessesInWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{2, 2}}, result)
+
+	result, err = storageAdapter.IncrementAccessesInWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 0, Counts: []int64{2, 2}}, result)
+
+	now = now.Add(time.Second * 2)
+	result, err = storageAdapter.IncrementAccessesInWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1, 3}}, result)
+
+	now = now.Add(time.Second * 2)
+	result, err = storageAdapter.IncrementAccessesInWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 1, Counts: []int64{0, 3}}, result)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestIncrementFixedWindows() {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+	limits := []*WindowLimit{
+		{MaxAccesses: 2, WindowMilliseconds: 1000},
+		{MaxAccesses: 4, WindowMilliseconds: 60000},
+	}
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	now := time.UnixMilli(1700000040000)
+	storageAdapter.now = func() time.Time { return now }
+
+	expectedResults := []*WindowsResult{
+		{Success: true, ExceededIndex: -1, Counts: []int64{1, 1}},
+		{Success: true, ExceededIndex: -1, Counts: []int64{2, 2}},
+		{Success: false, ExceededIndex: 0, Counts: []int64{3, 3}},
+	}
+
+	for _, expected := range expectedResults {
+		result, err := storageAdapter.IncrementFixedWindows(ctx, keyType, keyValue, limits)
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), expected, result)
+	}
+
+	now = now.Add(time.Millisecond * 999)
+	result, err := storageAdapter.IncrementFixedWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 0, Counts: []int64{4, 4}}, result)
+
+	now = now.Add(time.Millisecond)
+	result, err = storageAdapter.IncrementFixedWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 1, Counts: []int64{1, 5}}, result)
+
+	now = now.Add(time.Minute)
+	result, err = storageAdapter.IncrementFixedWindows(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1, 1}}, result)
+
+	keyData := (*storageAdapter.counters[keyType])[keyValue]
+	assert.Len(s.T(), *keyData, 2)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestIncrementSlidingWindowCounters() {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+	limits := []*WindowLimit{{MaxAccesses: 4, WindowMilliseconds: 1000}}
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	now := time.UnixMilli(1700000040000)
+	storageAdapter.now = func() time.Time { return now }
+
+	for i := int64(1); i <= 4; i++ {
+		result, err := storageAdapter.IncrementSlidingWindowCounters(ctx, keyType, keyValue, limits)
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{i}}, result)
+	}
+
+	result, err := storageAdapter.IncrementSlidingWindowCounters(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 0, Counts: []int64{4}}, result)
+
+	now = now.Add(time.Millisecond * 1250)
+	result, err = storageAdapter.IncrementSlidingWindowCounters(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{4}}, result)
+
+	result, err = storageAdapter.IncrementSlidingWindowCounters(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 0, Counts: []int64{4}}, result)
+
+	now = now.Add(time.Millisecond * 250)
+	result, err = storageAdapter.IncrementSlidingWindowCounters(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{4}}, result)
+
+	now = now.Add(time.Second * 2)
+	result, err = storageAdapter.IncrementSlidingWindowCounters(ctx, keyType, keyValue, limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1}}, result)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestIncrementSlidingWindowCounters_UniformTrafficMatchesSlidingLog() {
+	maxAccesses := int64(50)
+	window := time.Second
+
+	exact, approximated := s.replayTraffic(maxAccesses, window, func(step int) (time.Duration, int) {
+		return time.Duration(step) * time.Millisecond * 10, 1
+	}, 1000)
+
+	assert.InDelta(s.T(), len(exact), len(approximated), float64(len(exact))*0.02)
+	assert.LessOrEqual(s.T(), maxInWindow(exact, window), maxAccesses)
+	assert.LessOrEqual(s.T(), maxInWindow(approximated, window), maxAccesses+1)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestIncrementSlidingWindowCounters_BoundaryBurstErrorBound() {
+	maxAccesses := int64(10)
+	window := time.Second
+
+	exact, approximated := s.replayTraffic(maxAccesses, window, func(step int) (time.Duration, int) {
+		if step == 0 {
+			return time.Millisecond * 999, int(maxAccesses) * 2
+		}
+		return time.Millisecond*999 + time.Duration(step)*time.Millisecond*10, 1
+	}, 200)
+
+	assert.LessOrEqual(s.T(), maxInWindow(exact, window), maxAccesses)
+	assert.Greater(s.T(), maxInWindow(approximated, window), maxAccesses)
+	assert.LessOrEqual(s.T(), maxInWindow(approximated, window), maxAccesses*2)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) replayTraffic(maxAccesses int64, window time.Duration, traffic func(step int) (time.Duration, int), steps int) ([]time.Time, []time.Time) {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+	limits := []*WindowLimit{{MaxAccesses: maxAccesses, WindowMilliseconds: window.Milliseconds()}}
+
+	start := time.UnixMilli(1700000040000)
+	now := start
+
+	exactAdapter := NewRateLimitMemoryStorageAdapter()
+	exactAdapter.now = func() time.Time { return now }
+	approximatedAdapter := NewRateLimitMemoryStorageAdapter()
+	approximatedAdapter.now = func() time.Time { return now }
+
+	exact := []time.Time{}
+	approximated := []time.Time{}
+	for step := 0; step < steps; step++ {
+		offset, requests := traffic(step)
+		now = start.Add(offset)
+		for i := 0; i < requests; i++ {
+			success, _, err := exactAdapter.IncrementAccessesInWindow(ctx, keyType, keyValue, maxAccesses, window.Milliseconds())
+			assert.Nil(s.T(), err)
+			if success {
+				exact = append(exact, now)
+			}
+
+			result, err := approximatedAdapter.IncrementSlidingWindowCounters(ctx, keyType, keyValue, limits)
+			assert.Nil(s.T(), err)
+			if result.Success {
+				approximated = append(approximated, now)
+			}
+		}
+	}
+
+	return exact, approximated
+}
+
+func maxInWindow(accesses []time.Time, window time.Duration) int64 {
+	max := int64(0)
+	for i, end := range accesses {
+		count := int64(0)
+		for _, access := range accesses[:i+1] {
+			if end.Sub(access) < window {
+				count++
+			}
+		}
+		if count > max {
+			max = count
+		}
+	}
+	return max
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestTakeToken() {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	now := time.Now()
+	storageAdapter.now = func() time.Time { return now }
+
+	expectedResults := [][]interface{}{
+		{true, int64(2), nil},
+		{true, int64(1), nil},
+		{true, int64(0), nil},
+		{false, int64(0), nil},
+	}
+
+	for _, val := range expectedResults {
+		success, remaining, err := storageAdapter.TakeToken(ctx, keyType, keyValue, 3, 2)
+		assert.Equal(s.T(), val[0], success)
+		assert.Equal(s.T(), val[1], remaining)
+		assert.Equal(s.T(), val[2], err)
+	}
+
+	now = now.Add(time.Millisecond * 500)
+	success, remaining, err := storageAdapter.TakeToken(ctx, keyType, keyValue, 3, 2)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(0), remaining)
+	assert.Nil(s.T(), err)
+
+	now = now.Add(time.Minute)
+	success, remaining, err = storageAdapter.TakeToken(ctx, keyType, keyValue, 3, 2)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(2), remaining)
+	assert.Nil(s.T(), err)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestCheckCellRate() {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	now := time.Now()
+	storageAdapter.now = func() time.Time { return now }
+
+	expectedResults := [][]interface{}{
+		{true, int64(2), nil},
+		{true, int64(1), nil},
+		{true, int64(0), nil},
+		{false, int64(0), nil},
+	}
+
+	for _, val := range expectedResults {
+		success, remaining, err := storageAdapter.CheckCellRate(ctx, keyType, keyValue, 10, 1000, 3)
+		assert.Equal(s.T(), val[0], success)
+		assert.Equal(s.T(), val[1], remaining)
+		assert.Equal(s.T(), val[2], err)
+	}
+
+	now = now.Add(time.Millisecond * 100)
+	success, remaining, err := storageAdapter.CheckCellRate(ctx, keyType, keyValue, 10, 1000, 3)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(0), remaining)
+	assert.Nil(s.T(), err)
+
+	success, _, _ = storageAdapter.CheckCellRate(ctx, keyType, keyValue, 10, 1000, 3)
+	assert.False(s.T(), success)
+
+	now = now.Add(time.Minute)
+	success, remaining, err = storageAdapter.CheckCellRate(ctx, keyType, keyValue, 10, 1000, 3)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(2), remaining)
+	assert.Nil(s.T(), err)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestCheckCellRate_ZeroRate() {
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	success, remaining, err := storageAdapter.CheckCellRate(s.context, "IP", "127.0.0.1", 0, 1000, 3)
+	assert.False(s.T(), success)
+	assert.Equal(s.T(), int64(0), remaining)
+	assert.Nil(s.T(), err)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestCheck() {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	now := time.Now()
+	storageAdapter.now = func() time.Time { return now }
+
+	request := &CheckRequest{
+		Algorithm:             AlgorithmFixedWindow,
+		Limits:                []*WindowLimit{{MaxAccesses: 2, WindowMilliseconds: 1000}, {MaxAccesses: 10, WindowMilliseconds: 60000}},
+		BlockTimeMilliseconds: 500,
+	}
+
+	for i := int64(1); i <= 2; i++ {
+		result, err := storageAdapter.Check(ctx, keyType, keyValue, request)
+		assert.Nil(s.T(), err)
+		assert.True(s.T(), result.Success)
+		assert.Nil(s.T(), result.BlockedUntil)
+		assert.Equal(s.T(), []int64{i, i}, result.Counts)
+	}
+
+	result, err := storageAdapter.Check(ctx, keyType, keyValue, request)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), result.Success)
+	assert.False(s.T(), result.AlreadyBlocked)
+	assert.Equal(s.T(), 0, result.ExceededIndex)
+	assert.Equal(s.T(), now.Add(time.Millisecond*500), *result.BlockedUntil)
+
+	result, err = storageAdapter.Check(ctx, keyType, keyValue, request)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), result.Success)
+	assert.True(s.T(), result.AlreadyBlocked)
+	assert.Equal(s.T(), now.Add(time.Millisecond*500), *result.BlockedUntil)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestCheck_TokenBucket() {
+	ctx := s.context
+	keyType := "IP"
+	keyValue := "127.0.0.1"
+
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+
+	request := &CheckRequest{
+		Algorithm:             AlgorithmTokenBucket,
+		BucketCapacity:        2,
+		RefillRatePerSecond:   1,
+		BlockTimeMilliseconds: 500,
+	}
+
+	result, err := storageAdapter.Check(ctx, keyType, keyValue, request)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), result.Success)
+	assert.Equal(s.T(), int64(1), result.Remaining)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestCheck_UnknownAlgorithm() {
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+
+	result, err := storageAdapter.Check(s.context, "IP", "127.0.0.1", &CheckRequest{Algorithm: "unknown"})
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), result)
+}
+
+func (s *RateLimitMemoryStorageAdapterTestSuite) TestCheckBatch() {
+	storageAdapter := NewRateLimitMemoryStorageAdapter()
+	request := &CheckRequest{
+		Algorithm:             AlgorithmFixedWindow,
+		Limits:                []*WindowLimit{{MaxAccesses: 1, WindowMilliseconds: 60000}},
+		BlockTimeMilliseconds: 500,
+	}
+	requests := []*BatchCheckRequest{
+		{KeyType: "TOKEN", Key: "abc", Request: request},
+		{KeyType: "IP", Key: "127.0.0.1", Request: request},
+	}
+
+	results, err := storageAdapter.CheckBatch(s.context, requests)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), results, 2)
+	assert.True(s.T(), results[0].Success)
+	assert.True(s.T(), results[1].Success)
+
+	results, err = storageAdapter.CheckBatch(s.context, requests[1:])
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), results, 1)
+	assert.False(s.T(), results[0].Success)
+	assert.NotNil(s.T(), results[0].BlockedUntil)
+}
+
 func (s *RateLimitMemoryStorageAdapterTestSuite) TestAddBlockGetBlock_SameTypeAndValue() {
 	ctx := s.context
 	keyType := "IP"
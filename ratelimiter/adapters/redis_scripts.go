@@ -0,0 +1,233 @@
+package adapters
+
+import "github.com/redis/go-redis/v9"
+
+const tokenBucketFunction = `
+local function token_bucket(keys, args)
+	local capacity = tonumber(args[1])
+	local refill_rate = tonumber(args[2])
+
+	local time = redis.call('TIME')
+	local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+	local bucket = redis.call('HMGET', keys[1], 'tokens', 'updated_at')
+	local tokens = tonumber(bucket[1])
+	local updated_at = tonumber(bucket[2])
+	if tokens == nil or updated_at == nil then
+		tokens = capacity
+		updated_at = now
+	end
+
+	if now > updated_at then
+		tokens = tokens + (now - updated_at) * refill_rate / 1000000
+	end
+	if tokens > capacity then
+		tokens = capacity
+	end
+
+	local allowed = 0
+	local exceeded = 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+		allowed = 1
+		exceeded = -1
+	end
+
+	redis.call('HSET', keys[1], 'tokens', tostring(tokens), 'updated_at', now)
+	if refill_rate > 0 then
+		redis.call('PEXPIRE', keys[1], math.ceil(capacity * 1000 / refill_rate))
+	end
+
+	return {allowed, exceeded, math.floor(tokens)}
+end
+`
+
+const cellRateFunction = `
+local function gcra(keys, args)
+	local emission_interval = tonumber(args[1])
+	local burst_tolerance = tonumber(args[2])
+
+	local time = redis.call('TIME')
+	local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+	local theoretical_arrival = tonumber(redis.call('GET', keys[1]))
+	if theoretical_arrival == nil or theoretical_arrival < now then
+		theoretical_arrival = now
+	end
+
+	local new_theoretical_arrival = theoretical_arrival + emission_interval
+	local allow_at = new_theoretical_arrival - burst_tolerance
+
+	if now < allow_at then
+		return {0, 0, 0}
+	end
+
+	redis.call('SET', keys[1], new_theoretical_arrival, 'PX', math.ceil((new_theoretical_arrival - now) / 1000))
+
+	return {1, -1, math.floor((now - allow_at) / emission_interval)}
+end
+`
+
+const slidingLogFunction = `
+local function sliding_log(keys, args)
+	local member = args[1]
+	local longest_window = tonumber(args[2])
+
+	local time = redis.call('TIME')
+	local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+	redis.call('ZREMRANGEBYSCORE', keys[1], 0, now - longest_window)
+
+	local exceeded = -1
+	local counts = {}
+	for i = 3, #args, 2 do
+		local max_accesses = tonumber(args[i])
+		local window = tonumber(args[i + 1])
+		local count = redis.call('ZCOUNT', keys[1], string.format('(%d', now - window), '+inf')
+		table.insert(counts, count)
+		if exceeded == -1 and count >= max_accesses then
+			exceeded = #counts - 1
+		end
+	end
+
+	if exceeded ~= -1 then
+		return {0, exceeded, unpack(counts)}
+	end
+
+	redis.call('ZADD', keys[1], now, member)
+	redis.call('PEXPIRE', keys[1], math.ceil(longest_window / 1000))
+
+	for i = 1, #counts do
+		counts[i] = counts[i] + 1
+	end
+
+	return {1, exceeded, unpack(counts)}
+end
+`
+
+const fixedWindowFunction = `
+local function fixed_window(keys, args)
+	local time = redis.call('TIME')
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+	local exceeded = -1
+	local counts = {}
+	for i = 1, #args, 2 do
+		local key = keys[(i + 1) / 2]
+		local window = tonumber(args[i + 1])
+		local index = math.floor(now / window)
+
+		local counter = redis.call('HMGET', key, 'index', 'count')
+		local count = 0
+		if tonumber(counter[1]) == index then
+			count = tonumber(counter[2])
+		end
+		count = count + 1
+
+		redis.call('HSET', key, 'index', index, 'count', count)
+		redis.call('PEXPIREAT', key, (index + 1) * window)
+		table.insert(counts, count)
+		if exceeded == -1 and count > tonumber(args[i]) then
+			exceeded = #counts - 1
+		end
+	end
+
+	if exceeded ~= -1 then
+		return {0, exceeded, unpack(counts)}
+	end
+
+	return {1, exceeded, unpack(counts)}
+end
+`
+
+const slidingWindowCounterFunction = `
+local function sliding_window_counter(keys, args)
+	local time = redis.call('TIME')
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+	local exceeded = -1
+	local counts = {}
+	local counters = {}
+	for i = 1, #args, 2 do
+		local max_accesses = tonumber(args[i])
+		local window = tonumber(args[i + 1])
+		local index = math.floor(now / window)
+		local elapsed = (now - index * window) / window
+
+		local counter = redis.call('HMGET', keys[(i + 1) / 2], 'index', 'current', 'previous')
+		local stored_index = tonumber(counter[1])
+		local current = 0
+		local previous = 0
+		if stored_index == index then
+			current = tonumber(counter[2])
+			previous = tonumber(counter[3])
+		elseif stored_index == index - 1 then
+			previous = tonumber(counter[2])
+		end
+
+		local estimated = math.floor(previous * (1 - elapsed)) + current
+		table.insert(counts, estimated)
+		table.insert(counters, {index, current, previous, 2 * window})
+		if exceeded == -1 and estimated >= max_accesses then
+			exceeded = #counts - 1
+		end
+	end
+
+	if exceeded ~= -1 then
+		return {0, exceeded, unpack(counts)}
+	end
+
+	for i = 1, #counters do
+		local counter = counters[i]
+		redis.call('HSET', keys[i], 'index', counter[1], 'current', counter[2] + 1, 'previous', counter[3])
+		redis.call('PEXPIRE', keys[i], counter[4])
+		counts[i] = counts[i] + 1
+	end
+
+	return {1, exceeded, unpack(counts)}
+end
+`
+
+var tokenBucketScript = newAlgorithmScript(tokenBucketFunction, "token_bucket")
+var cellRateScript = newAlgorithmScript(cellRateFunction, "gcra")
+var slidingLogWindowsScript = newAlgorithmScript(slidingLogFunction, "sliding_log")
+var fixedWindowsScript = newAlgorithmScript(fixedWindowFunction, "fixed_window")
+var slidingWindowCountersScript = newAlgorithmScript(slidingWindowCounterFunction, "sliding_window_counter")
+
+var checkScript = redis.NewScript(`
+redis.replicate_commands()
+` + tokenBucketFunction + cellRateFunction + slidingLogFunction + fixedWindowFunction + slidingWindowCounterFunction + `
+local algorithms = {
+	token_bucket = token_bucket,
+	gcra = gcra,
+	sliding_log = sliding_log,
+	fixed_window = fixed_window,
+	sliding_window_counter = sliding_window_counter,
+}
+
+local blocked_until = redis.call('GET', KEYS[1])
+if blocked_until then
+	return {-1, blocked_until, -1}
+end
+
+local algorithm = algorithms[ARGV[1]]
+if algorithm == nil then
+	return redis.error_reply('unknown rate limiter algorithm ' .. ARGV[1])
+end
+
+local result = algorithm({unpack(KEYS, 2)}, {unpack(ARGV, 4)})
+if result[1] == 1 then
+	return {1, '', unpack(result, 2)}
+end
+
+local block_milliseconds = tonumber(ARGV[2])
+if block_milliseconds > 0 then
+	redis.call('SET', KEYS[1], ARGV[3], 'PX', block_milliseconds)
+end
+
+return {0, ARGV[3], unpack(result, 2)}
+`)
+
+func newAlgorithmScript(function string, name string) *redis.Script {
+	return redis.NewScript("redis.replicate_commands()\n" + function + "\nreturn " + name + "(KEYS, ARGV)\n")
+}
@@ -2,20 +2,25 @@ package adapters
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type rateLimitRedisStorageAdapter struct {
-	client *redis.Client
+	client     *redis.Client
+	instanceID string
+	sequence   atomic.Uint64
 }
 
 func NewRateLimitRedisStorageAdapter(address string, password string, db int64) *rateLimitRedisStorageAdapter {
 	adapter := rateLimitRedisStorageAdapter{}
+	adapter.instanceID = newInstanceID()
 
 	adapter.client = redis.NewClient(&redis.Options{
 		Addr:     address,
@@ -27,38 +32,213 @@ func NewRateLimitRedisStorageAdapter(address string, password string, db int64)
 }
 
 func (s *rateLimitRedisStorageAdapter) IncrementAccesses(ctx context.Context, keyType string, key string, maxAccesses int64) (bool, int64, error) {
-	redisKey := s.formatRedisKey("access", keyType, key)
+	return s.IncrementAccessesInWindow(ctx, keyType, key, maxAccesses, DefaultWindowMilliseconds)
+}
 
-	now := time.Now()
-	clearBefore := now.Add(-time.Second)
+func (s *rateLimitRedisStorageAdapter) IncrementAccessesInWindow(ctx context.Context, keyType string, key string, maxAccesses int64, windowMilliseconds int64) (bool, int64, error) {
+	result, err := s.IncrementAccessesInWindows(ctx, keyType, key, []*WindowLimit{{MaxAccesses: maxAccesses, WindowMilliseconds: windowMilliseconds}})
+	if err != nil {
+		return false, 0, err
+	}
 
-	pipeline := s.client.Pipeline()
+	return result.Success, result.Counts[0], nil
+}
 
-	pipeline.ZRemRangeByScore(ctx, redisKey, "0", strconv.FormatInt(clearBefore.UnixMicro(), 10))
-	count := pipeline.ZCard(ctx, redisKey)
+func (s *rateLimitRedisStorageAdapter) IncrementAccessesInWindows(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error) {
+	keys, args := s.slidingLogArguments(keyType, key, limits)
+	return s.runWindowsScript(ctx, slidingLogWindowsScript, keys, args)
+}
 
-	_, err := pipeline.Exec(ctx)
+func (s *rateLimitRedisStorageAdapter) IncrementFixedWindows(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error) {
+	keys, args := s.fixedWindowArguments(keyType, key, limits)
+	return s.runWindowsScript(ctx, fixedWindowsScript, keys, args)
+}
+
+func (s *rateLimitRedisStorageAdapter) IncrementSlidingWindowCounters(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error) {
+	keys, args := s.slidingWindowCounterArguments(keyType, key, limits)
+	return s.runWindowsScript(ctx, slidingWindowCountersScript, keys, args)
+}
+
+func (s *rateLimitRedisStorageAdapter) TakeToken(ctx context.Context, keyType string, key string, capacity int64, refillRatePerSecond int64) (bool, int64, error) {
+	keys, args := s.tokenBucketArguments(keyType, key, capacity, refillRatePerSecond)
+
+	result, err := s.runScript(ctx, tokenBucketScript, keys, args...).Int64Slice()
 	if err != nil {
 		logRedisError(err)
 		return false, 0, err
 	}
 
-	if count.Val() >= maxAccesses {
-		return false, count.Val(), nil
-	}
+	return result[0] == 1, result[2], nil
+}
 
-	pipeline = s.client.Pipeline()
+func (s *rateLimitRedisStorageAdapter) CheckCellRate(ctx context.Context, keyType string, key string, maxRequests int64, windowMilliseconds int64, burst int64) (bool, int64, error) {
+	if maxRequests <= 0 {
+		return false, 0, nil
+	}
 
-	pipeline.ZAdd(ctx, redisKey, redis.Z{Score: float64(now.UnixMicro()), Member: now.Format(time.RFC3339Nano)})
-	pipeline.Expire(ctx, redisKey, time.Second)
+	keys, args := s.cellRateArguments(keyType, key, maxRequests, windowMilliseconds, burst)
 
-	_, err = pipeline.Exec(ctx)
+	result, err := s.runScript(ctx, cellRateScript, keys, args...).Int64Slice()
 	if err != nil {
 		logRedisError(err)
 		return false, 0, err
 	}
 
-	return true, count.Val() + 1, nil
+	return result[0] == 1, result[2], nil
+}
+
+func (s *rateLimitRedisStorageAdapter) Check(ctx context.Context, keyType string, key string, request *CheckRequest) (*CheckResult, error) {
+	keys, args, err := s.checkArguments(keyType, key, request)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.runScript(ctx, checkScript, keys, args...).Slice()
+	if err != nil {
+		logRedisError(err)
+		return nil, err
+	}
+
+	return parseCheckResult(request, values)
+}
+
+// CheckBatch sends the check script of every request in a single pipeline, so they cost one round trip.
+func (s *rateLimitRedisStorageAdapter) CheckBatch(ctx context.Context, requests []*BatchCheckRequest) ([]*CheckResult, error) {
+	keys := make([][]string, len(requests))
+	args := make([][]interface{}, len(requests))
+	for i, request := range requests {
+		var err error
+		keys[i], args[i], err = s.checkArguments(request.KeyType, request.Key, request.Request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cmds, err := s.runPipelinedScript(ctx, checkScript, keys, args)
+	if err != nil {
+		logRedisError(err)
+		return nil, err
+	}
+
+	results := []*CheckResult{}
+	for i, cmd := range cmds {
+		values, err := cmd.Slice()
+		if err != nil {
+			logRedisError(err)
+			return nil, err
+		}
+
+		result, err := parseCheckResult(requests[i].Request, values)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (s *rateLimitRedisStorageAdapter) checkArguments(keyType string, key string, request *CheckRequest) ([]string, []interface{}, error) {
+	keys, args, err := s.algorithmArguments(keyType, key, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockedUntil := time.Now().Add(time.Duration(request.BlockTimeMilliseconds) * time.Millisecond)
+
+	keys = append([]string{s.formatRedisKey("block", keyType, key)}, keys...)
+	args = append([]interface{}{request.Algorithm, request.BlockTimeMilliseconds, blockedUntil.Format(time.RFC3339Nano)}, args...)
+
+	return keys, args, nil
+}
+
+func (s *rateLimitRedisStorageAdapter) algorithmArguments(keyType string, key string, request *CheckRequest) ([]string, []interface{}, error) {
+	switch request.Algorithm {
+	case AlgorithmSlidingLog:
+		keys, args := s.slidingLogArguments(keyType, key, request.Limits)
+		return keys, args, nil
+	case AlgorithmFixedWindow:
+		keys, args := s.fixedWindowArguments(keyType, key, request.Limits)
+		return keys, args, nil
+	case AlgorithmSlidingWindowCounter:
+		keys, args := s.slidingWindowCounterArguments(keyType, key, request.Limits)
+		return keys, args, nil
+	case AlgorithmTokenBucket:
+		keys, args := s.tokenBucketArguments(keyType, key, request.BucketCapacity, request.RefillRatePerSecond)
+		return keys, args, nil
+	case AlgorithmGCRA:
+		keys, args := s.cellRateArguments(keyType, key, request.Limits[0].MaxAccesses, request.Limits[0].WindowMilliseconds, request.BucketCapacity)
+		return keys, args, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown rate limiter algorithm \"%s\"", request.Algorithm)
+	}
+}
+
+func (s *rateLimitRedisStorageAdapter) slidingLogArguments(keyType string, key string, limits []*WindowLimit) ([]string, []interface{}) {
+	redisKey := s.formatRedisKey("access", keyType, key)
+
+	longestWindow := int64(0)
+	args := []interface{}{}
+	for _, limit := range limits {
+		window := (time.Duration(limit.WindowMilliseconds) * time.Millisecond).Microseconds()
+		if window > longestWindow {
+			longestWindow = window
+		}
+		args = append(args, limit.MaxAccesses, window)
+	}
+	args = append([]interface{}{s.newAccessMember(), longestWindow}, args...)
+
+	return []string{redisKey}, args
+}
+
+func (s *rateLimitRedisStorageAdapter) fixedWindowArguments(keyType string, key string, limits []*WindowLimit) ([]string, []interface{}) {
+	keys := []string{}
+	args := []interface{}{}
+	for _, limit := range limits {
+		keys = append(keys, s.formatRedisKey("fixed", keyType, fmt.Sprintf("%s-%d", key, limit.WindowMilliseconds)))
+		args = append(args, limit.MaxAccesses, limit.WindowMilliseconds)
+	}
+
+	return keys, args
+}
+
+func (s *rateLimitRedisStorageAdapter) slidingWindowCounterArguments(keyType string, key string, limits []*WindowLimit) ([]string, []interface{}) {
+	keys := []string{}
+	args := []interface{}{}
+	for _, limit := range limits {
+		keys = append(keys, s.formatRedisKey("sliding", keyType, fmt.Sprintf("%s-%d", key, limit.WindowMilliseconds)))
+		args = append(args, limit.MaxAccesses, limit.WindowMilliseconds)
+	}
+
+	return keys, args
+}
+
+func (s *rateLimitRedisStorageAdapter) tokenBucketArguments(keyType string, key string, capacity int64, refillRatePerSecond int64) ([]string, []interface{}) {
+	return []string{s.formatRedisKey("bucket", keyType, key)}, []interface{}{capacity, refillRatePerSecond}
+}
+
+func (s *rateLimitRedisStorageAdapter) cellRateArguments(keyType string, key string, maxRequests int64, windowMilliseconds int64, burst int64) ([]string, []interface{}) {
+	redisKey := s.formatRedisKey("gcra", keyType, key)
+
+	if maxRequests <= 0 {
+		// no burst tolerance makes every arrival early, so the key is always denied
+		return []string{redisKey}, []interface{}{1, 0}
+	}
+
+	emissionInterval := (time.Duration(windowMilliseconds) * time.Millisecond).Microseconds() / maxRequests
+	burstTolerance := emissionInterval * burst
+
+	return []string{redisKey}, []interface{}{emissionInterval, burstTolerance}
+}
+
+func (s *rateLimitRedisStorageAdapter) runWindowsScript(ctx context.Context, script *redis.Script, keys []string, args []interface{}) (*WindowsResult, error) {
+	values, err := s.runScript(ctx, script, keys, args...).Int64Slice()
+	if err != nil {
+		logRedisError(err)
+		return nil, err
+	}
+
+	return &WindowsResult{Success: values[0] == 1, ExceededIndex: int(values[1]), Counts: values[2:]}, nil
 }
 
 func (s *rateLimitRedisStorageAdapter) GetBlock(ctx context.Context, keyType string, key string) (*time.Time, error) {
@@ -96,6 +276,43 @@ func (s *rateLimitRedisStorageAdapter) AddBlock(ctx context.Context, keyType str
 	return &blockedUntil, nil
 }
 
+func (s *rateLimitRedisStorageAdapter) runScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) *redis.Cmd {
+	cmd := script.EvalSha(ctx, s.client, keys, args...)
+	if err := cmd.Err(); err != nil && redis.HasErrorPrefix(err, "NOSCRIPT") {
+		if err := script.Load(ctx, s.client).Err(); err != nil {
+			return cmd
+		}
+		cmd = script.EvalSha(ctx, s.client, keys, args...)
+	}
+	return cmd
+}
+
+func (s *rateLimitRedisStorageAdapter) runPipelinedScript(ctx context.Context, script *redis.Script, keys [][]string, args [][]interface{}) ([]*redis.Cmd, error) {
+	run := func() ([]*redis.Cmd, error) {
+		cmds := make([]*redis.Cmd, len(keys))
+		_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i := range keys {
+				cmds[i] = script.EvalSha(ctx, pipe, keys[i], args[i]...)
+			}
+			return nil
+		})
+		return cmds, err
+	}
+
+	cmds, err := run()
+	if err != nil && redis.HasErrorPrefix(err, "NOSCRIPT") {
+		if err := script.Load(ctx, s.client).Err(); err != nil {
+			return nil, err
+		}
+		cmds, err = run()
+	}
+	return cmds, err
+}
+
+func (s *rateLimitRedisStorageAdapter) newAccessMember() string {
+	return fmt.Sprintf("%s-%d", s.instanceID, s.sequence.Add(1))
+}
+
 func (s *rateLimitRedisStorageAdapter) formatRedisKey(prefix string, keyType string, key string) string {
 	return fmt.Sprintf(
 		"%s-%s-%s",
@@ -105,6 +322,49 @@ func (s *rateLimitRedisStorageAdapter) formatRedisKey(prefix string, keyType str
 	)
 }
 
+func parseCheckResult(request *CheckRequest, values []interface{}) (*CheckResult, error) {
+	state, _ := values[0].(int64)
+	blockedUntilValue, _ := values[1].(string)
+	exceededIndex, _ := values[2].(int64)
+
+	result := &CheckResult{Success: state == 1, AlreadyBlocked: state == -1, ExceededIndex: int(exceededIndex)}
+
+	if blockedUntilValue != "" {
+		blockedUntil, err := time.Parse(time.RFC3339Nano, blockedUntilValue)
+		if err != nil {
+			return nil, err
+		}
+		result.BlockedUntil = &blockedUntil
+	}
+
+	if result.AlreadyBlocked {
+		return result, nil
+	}
+
+	counts := []int64{}
+	for _, value := range values[3:] {
+		count, _ := value.(int64)
+		counts = append(counts, count)
+	}
+
+	if request.Algorithm == AlgorithmTokenBucket || request.Algorithm == AlgorithmGCRA {
+		result.Counts = []int64{0}
+		result.Remaining = counts[0]
+	} else {
+		result.Counts = counts
+	}
+
+	return result, nil
+}
+
+func newInstanceID() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
+
 func logRedisError(err error) {
 	fmt.Printf(
 		"%s [REDIS STORAGE ADAPTER] ERROR: %s\n",
@@ -3,14 +3,19 @@ package adapters
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
 type RateLimitRedisStorageAdapter struct {
 	suite.Suite
-	context context.Context
+	context        context.Context
+	server         *miniredis.Miniredis
+	storageAdapter *rateLimitRedisStorageAdapter
+	now            time.Time
 }
 
 func TestRateLimitRedisStorageAdapter(t *testing.T) {
@@ -19,6 +24,17 @@ func TestRateLimitRedisStorageAdapter(t *testing.T) {
 
 func (s *RateLimitRedisStorageAdapter) SetupTest() {
 	s.context = context.Background()
+	s.server = miniredis.RunT(s.T())
+	s.storageAdapter = NewRateLimitRedisStorageAdapter(s.server.Addr(), "", 0)
+	s.now = time.UnixMilli(1700000040000)
+	s.server.SetTime(s.now)
+}
+
+// advance moves the redis server clock, which the scripts read with TIME, and expires its keys
+func (s *RateLimitRedisStorageAdapter) advance(duration time.Duration) {
+	s.now = s.now.Add(duration)
+	s.server.SetTime(s.now)
+	s.server.FastForward(duration)
 }
 
 func (s *RateLimitRedisStorageAdapter) TestNewRateLimitRedisStorageAdapter() {
@@ -31,3 +47,291 @@ func (s *RateLimitRedisStorageAdapter) TestFormatRedisKey() {
 	redisKeys := storageAdapter.formatRedisKey("block", "uSeR-ToKeN", "AbC123*#")
 	assert.Equal(s.T(), "block-user_token-AbC123*#", redisKeys)
 }
+
+func (s *RateLimitRedisStorageAdapter) TestNewAccessMember_Unique() {
+	storageAdapter := NewRateLimitRedisStorageAdapter("", "", 0)
+	anotherStorageAdapter := NewRateLimitRedisStorageAdapter("", "", 0)
+
+	members := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		members[storageAdapter.newAccessMember()] = true
+		members[anotherStorageAdapter.newAccessMember()] = true
+	}
+
+	assert.Len(s.T(), members, 200)
+}
+
+func (s *RateLimitRedisStorageAdapter) TestParseCheckResult_Denied() {
+	blockedUntil := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	request := &CheckRequest{Algorithm: AlgorithmFixedWindow}
+
+	result, err := parseCheckResult(request, []interface{}{int64(0), blockedUntil.Format(time.RFC3339Nano), int64(1), int64(3), int64(501)})
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), result.Success)
+	assert.False(s.T(), result.AlreadyBlocked)
+	assert.Equal(s.T(), 1, result.ExceededIndex)
+	assert.Equal(s.T(), []int64{3, 501}, result.Counts)
+	assert.True(s.T(), blockedUntil.Equal(*result.BlockedUntil))
+}
+
+func (s *RateLimitRedisStorageAdapter) TestParseCheckResult_AlreadyBlocked() {
+	blockedUntil := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	request := &CheckRequest{Algorithm: AlgorithmSlidingLog}
+
+	result, err := parseCheckResult(request, []interface{}{int64(-1), blockedUntil.Format(time.RFC3339Nano), int64(-1)})
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), result.AlreadyBlocked)
+	assert.True(s.T(), blockedUntil.Equal(*result.BlockedUntil))
+}
+
+func (s *RateLimitRedisStorageAdapter) TestParseCheckResult_TokenBucket() {
+	request := &CheckRequest{Algorithm: AlgorithmTokenBucket}
+
+	result, err := parseCheckResult(request, []interface{}{int64(1), "", int64(-1), int64(4)})
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), result.Success)
+	assert.Nil(s.T(), result.BlockedUntil)
+	assert.Equal(s.T(), int64(4), result.Remaining)
+}
+
+func (s *RateLimitRedisStorageAdapter) TestIncrementAccessesInWindows() {
+	limits := []*WindowLimit{
+		{MaxAccesses: 2, WindowMilliseconds: 1000},
+		{MaxAccesses: 3, WindowMilliseconds: 60000},
+	}
+
+	expectedResults := []*WindowsResult{
+		{Success: true, ExceededIndex: -1, Counts: []int64{1, 1}},
+		{Success: true, ExceededIndex: -1, Counts: []int64{2, 2}},
+		{Success: false, ExceededIndex: 0, Counts: []int64{2, 2}},
+	}
+
+	for _, expected := range expectedResults {
+		result, err := s.storageAdapter.IncrementAccessesInWindows(s.context, "IP", "127.0.0.1", limits)
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), expected, result)
+	}
+	assert.Equal(s.T(), time.Minute, s.server.TTL("access-ip-127.0.0.1"))
+
+	s.advance(time.Second)
+	result, err := s.storageAdapter.IncrementAccessesInWindows(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1, 3}}, result)
+
+	result, err = s.storageAdapter.IncrementAccessesInWindows(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 1, Counts: []int64{1, 3}}, result)
+
+	s.advance(time.Minute)
+	assert.False(s.T(), s.server.Exists("access-ip-127.0.0.1"))
+}
+
+func (s *RateLimitRedisStorageAdapter) TestIncrementAccessesInWindows_UniqueMemberAcrossReplicas() {
+	anotherStorageAdapter := NewRateLimitRedisStorageAdapter(s.server.Addr(), "", 0)
+	limits := []*WindowLimit{{MaxAccesses: 10, WindowMilliseconds: 1000}}
+
+	for i := 0; i < 2; i++ {
+		_, err := s.storageAdapter.IncrementAccessesInWindows(s.context, "IP", "127.0.0.1", limits)
+		assert.Nil(s.T(), err)
+		_, err = anotherStorageAdapter.IncrementAccessesInWindows(s.context, "IP", "127.0.0.1", limits)
+		assert.Nil(s.T(), err)
+	}
+
+	members, err := s.server.ZMembers("access-ip-127.0.0.1")
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), members, 4)
+	for _, member := range members {
+		score, err := s.server.ZScore("access-ip-127.0.0.1", member)
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), float64(s.now.UnixMicro()), score)
+	}
+}
+
+func (s *RateLimitRedisStorageAdapter) TestIncrementFixedWindows() {
+	limits := []*WindowLimit{
+		{MaxAccesses: 2, WindowMilliseconds: 1000},
+		{MaxAccesses: 4, WindowMilliseconds: 60000},
+	}
+
+	expectedResults := []*WindowsResult{
+		{Success: true, ExceededIndex: -1, Counts: []int64{1, 1}},
+		{Success: true, ExceededIndex: -1, Counts: []int64{2, 2}},
+		{Success: false, ExceededIndex: 0, Counts: []int64{3, 3}},
+	}
+
+	for _, expected := range expectedResults {
+		result, err := s.storageAdapter.IncrementFixedWindows(s.context, "IP", "127.0.0.1", limits)
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), expected, result)
+	}
+	assert.Equal(s.T(), time.Second, s.server.TTL("fixed-ip-127.0.0.1-1000"))
+	assert.Equal(s.T(), time.Minute, s.server.TTL("fixed-ip-127.0.0.1-60000"))
+
+	s.advance(time.Millisecond * 999)
+	result, err := s.storageAdapter.IncrementFixedWindows(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 0, Counts: []int64{4, 4}}, result)
+	assert.Equal(s.T(), time.Millisecond, s.server.TTL("fixed-ip-127.0.0.1-1000"))
+
+	s.advance(time.Millisecond)
+	result, err = s.storageAdapter.IncrementFixedWindows(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 1, Counts: []int64{1, 5}}, result)
+
+	s.advance(time.Minute)
+	result, err = s.storageAdapter.IncrementFixedWindows(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1, 1}}, result)
+}
+
+func (s *RateLimitRedisStorageAdapter) TestIncrementSlidingWindowCounters() {
+	limits := []*WindowLimit{{MaxAccesses: 4, WindowMilliseconds: 1000}}
+
+	for i := int64(1); i <= 4; i++ {
+		result, err := s.storageAdapter.IncrementSlidingWindowCounters(s.context, "IP", "127.0.0.1", limits)
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{i}}, result)
+	}
+	assert.Equal(s.T(), time.Second*2, s.server.TTL("sliding-ip-127.0.0.1-1000"))
+
+	result, err := s.storageAdapter.IncrementSlidingWindowCounters(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: false, ExceededIndex: 0, Counts: []int64{4}}, result)
+
+	// half of the next window: the previous window weighs half of its 4 accesses
+	s.advance(time.Millisecond * 1500)
+	result, err = s.storageAdapter.IncrementSlidingWindowCounters(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{3}}, result)
+
+	result, err = s.storageAdapter.IncrementSlidingWindowCounters(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{4}}, result)
+
+	s.advance(time.Second * 2)
+	assert.False(s.T(), s.server.Exists("sliding-ip-127.0.0.1-1000"))
+	result, err = s.storageAdapter.IncrementSlidingWindowCounters(s.context, "IP", "127.0.0.1", limits)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1}}, result)
+}
+
+func (s *RateLimitRedisStorageAdapter) TestTakeToken() {
+	for i := int64(1); i >= 0; i-- {
+		success, remaining, err := s.storageAdapter.TakeToken(s.context, "IP", "127.0.0.1", 2, 1)
+		assert.Nil(s.T(), err)
+		assert.True(s.T(), success)
+		assert.Equal(s.T(), i, remaining)
+	}
+	assert.Equal(s.T(), time.Second*2, s.server.TTL("bucket-ip-127.0.0.1"))
+
+	success, remaining, err := s.storageAdapter.TakeToken(s.context, "IP", "127.0.0.1", 2, 1)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), success)
+	assert.Equal(s.T(), int64(0), remaining)
+
+	s.advance(time.Second)
+	success, remaining, err = s.storageAdapter.TakeToken(s.context, "IP", "127.0.0.1", 2, 1)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(0), remaining)
+}
+
+func (s *RateLimitRedisStorageAdapter) TestCheckCellRate() {
+	success, remaining, err := s.storageAdapter.CheckCellRate(s.context, "IP", "127.0.0.1", 2, 1000, 2)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(1), remaining)
+	assert.Equal(s.T(), time.Millisecond*500, s.server.TTL("gcra-ip-127.0.0.1"))
+
+	success, remaining, err = s.storageAdapter.CheckCellRate(s.context, "IP", "127.0.0.1", 2, 1000, 2)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(0), remaining)
+	assert.Equal(s.T(), time.Second, s.server.TTL("gcra-ip-127.0.0.1"))
+
+	success, _, err = s.storageAdapter.CheckCellRate(s.context, "IP", "127.0.0.1", 2, 1000, 2)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), success)
+
+	s.advance(time.Millisecond * 500)
+	success, remaining, err = s.storageAdapter.CheckCellRate(s.context, "IP", "127.0.0.1", 2, 1000, 2)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), success)
+	assert.Equal(s.T(), int64(0), remaining)
+}
+
+func (s *RateLimitRedisStorageAdapter) TestCheck() {
+	request := &CheckRequest{
+		Algorithm:             AlgorithmFixedWindow,
+		Limits:                []*WindowLimit{{MaxAccesses: 2, WindowMilliseconds: 1000}, {MaxAccesses: 10, WindowMilliseconds: 60000}},
+		BlockTimeMilliseconds: 500,
+	}
+
+	for i := int64(1); i <= 2; i++ {
+		result, err := s.storageAdapter.Check(s.context, "IP", "127.0.0.1", request)
+		assert.Nil(s.T(), err)
+		assert.True(s.T(), result.Success)
+		assert.Nil(s.T(), result.BlockedUntil)
+		assert.Equal(s.T(), []int64{i, i}, result.Counts)
+	}
+
+	result, err := s.storageAdapter.Check(s.context, "IP", "127.0.0.1", request)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), result.Success)
+	assert.False(s.T(), result.AlreadyBlocked)
+	assert.Equal(s.T(), 0, result.ExceededIndex)
+	assert.NotNil(s.T(), result.BlockedUntil)
+	assert.Equal(s.T(), time.Millisecond*500, s.server.TTL("block-ip-127.0.0.1"))
+
+	result, err = s.storageAdapter.Check(s.context, "IP", "127.0.0.1", request)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), result.AlreadyBlocked)
+
+	s.advance(time.Millisecond * 500)
+	result, err = s.storageAdapter.Check(s.context, "IP", "127.0.0.1", request)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), result.AlreadyBlocked)
+}
+
+func (s *RateLimitRedisStorageAdapter) TestCheck_EveryAlgorithm() {
+	for _, algorithm := range []string{AlgorithmSlidingLog, AlgorithmFixedWindow, AlgorithmSlidingWindowCounter, AlgorithmTokenBucket, AlgorithmGCRA} {
+		request := &CheckRequest{
+			Algorithm:           algorithm,
+			Limits:              []*WindowLimit{{MaxAccesses: 1, WindowMilliseconds: 1000}},
+			BucketCapacity:      1,
+			RefillRatePerSecond: 1,
+		}
+
+		result, err := s.storageAdapter.Check(s.context, "IP", algorithm, request)
+		assert.Nil(s.T(), err, algorithm)
+		assert.True(s.T(), result.Success, algorithm)
+
+		result, err = s.storageAdapter.Check(s.context, "IP", algorithm, request)
+		assert.Nil(s.T(), err, algorithm)
+		assert.False(s.T(), result.Success, algorithm)
+	}
+}
+
+func (s *RateLimitRedisStorageAdapter) TestCheckBatch() {
+	request := &CheckRequest{
+		Algorithm:             AlgorithmFixedWindow,
+		Limits:                []*WindowLimit{{MaxAccesses: 1, WindowMilliseconds: 60000}},
+		BlockTimeMilliseconds: 500,
+	}
+	requests := []*BatchCheckRequest{
+		{KeyType: "TOKEN", Key: "abc", Request: request},
+		{KeyType: "IP", Key: "127.0.0.1", Request: request},
+	}
+
+	results, err := s.storageAdapter.CheckBatch(s.context, requests)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), results, 2)
+	assert.True(s.T(), results[0].Success)
+	assert.True(s.T(), results[1].Success)
+
+	results, err = s.storageAdapter.CheckBatch(s.context, requests[1:])
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), results, 1)
+	assert.False(s.T(), results[0].Success)
+	assert.NotNil(s.T(), results[0].BlockedUntil)
+}
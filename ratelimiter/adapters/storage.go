@@ -2,11 +2,94 @@ package adapters
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+const AlgorithmSlidingLog = "sliding_log"
+const AlgorithmTokenBucket = "token_bucket"
+const AlgorithmGCRA = "gcra"
+const AlgorithmFixedWindow = "fixed_window"
+const AlgorithmSlidingWindowCounter = "sliding_window_counter"
+
+type WindowLimit struct {
+	MaxAccesses        int64
+	WindowMilliseconds int64
+}
+
+type WindowsResult struct {
+	Success       bool
+	ExceededIndex int
+	Counts        []int64
+}
+
+type CheckRequest struct {
+	Algorithm             string
+	Limits                []*WindowLimit
+	BucketCapacity        int64
+	RefillRatePerSecond   int64
+	BlockTimeMilliseconds int64
+}
+
+type CheckResult struct {
+	Success        bool
+	AlreadyBlocked bool
+	BlockedUntil   *time.Time
+	ExceededIndex  int
+	Counts         []int64
+	Remaining      int64
+}
+
+type BatchCheckRequest struct {
+	KeyType string
+	Key     string
+	Request *CheckRequest
+}
+
+// DefaultWindowMilliseconds is the window of RateLimitStorageAdapter.IncrementAccesses.
+const DefaultWindowMilliseconds = 1000
+
+var ErrAlgorithmNotSupported = errors.New("algorithm not supported by storage adapter")
+
+// RateLimitStorageAdapter counts the accesses of a key in the last second. The other algorithms and window lengths
+// need the optional interfaces below.
 type RateLimitStorageAdapter interface {
 	IncrementAccesses(ctx context.Context, keyType string, key string, maxAccesses int64) (bool, int64, error)
 	GetBlock(ctx context.Context, keyType string, key string) (*time.Time, error)
 	AddBlock(ctx context.Context, keyType string, key string, milliseconds int64) (*time.Time, error)
 }
+
+// RateLimitWindowStorageAdapter counts the accesses of a key in a sliding log of any length.
+type RateLimitWindowStorageAdapter interface {
+	IncrementAccessesInWindow(ctx context.Context, keyType string, key string, maxAccesses int64, windowMilliseconds int64) (bool, int64, error)
+}
+
+// RateLimitMultiWindowStorageAdapter counts the accesses of a key in several sliding logs at once.
+type RateLimitMultiWindowStorageAdapter interface {
+	IncrementAccessesInWindows(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error)
+}
+
+type RateLimitFixedWindowStorageAdapter interface {
+	IncrementFixedWindows(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error)
+}
+
+type RateLimitSlidingWindowCounterStorageAdapter interface {
+	IncrementSlidingWindowCounters(ctx context.Context, keyType string, key string, limits []*WindowLimit) (*WindowsResult, error)
+}
+
+type RateLimitTokenBucketStorageAdapter interface {
+	TakeToken(ctx context.Context, keyType string, key string, capacity int64, refillRatePerSecond int64) (bool, int64, error)
+}
+
+type RateLimitGCRAStorageAdapter interface {
+	CheckCellRate(ctx context.Context, keyType string, key string, maxRequests int64, windowMilliseconds int64, burst int64) (bool, int64, error)
+}
+
+type RateLimitCheckStorageAdapter interface {
+	Check(ctx context.Context, keyType string, key string, request *CheckRequest) (*CheckResult, error)
+}
+
+// RateLimitBatchCheckStorageAdapter is implemented by adapters that run several checks in a single storage call.
+type RateLimitBatchCheckStorageAdapter interface {
+	CheckBatch(ctx context.Context, requests []*BatchCheckRequest) ([]*CheckResult, error)
+}
@@ -0,0 +1,230 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+const HeaderForwarded = "Forwarded"
+const HeaderXForwardedFor = "X-Forwarded-For"
+const HeaderXRealIP = "X-Real-IP"
+
+const defaultClientIPHeader = HeaderXForwardedFor
+
+// GetClientIPHeader returns the one header the trusted proxies write. Other forwarding headers are ignored, since the
+// client can send them through proxies that do not overwrite them.
+func (c *LimiterConfig) GetClientIPHeader() string {
+	if c.ClientIPHeader != "" {
+		return c.ClientIPHeader
+	}
+	return defaultClientIPHeader
+}
+
+// GetClientIP returns the remote address, or the address forwarded by the trusted proxies in front of it.
+// The forwarding chain is walked right to left and the first address that is not a trusted proxy is the client.
+func (c *LimiterConfig) GetClientIP(r *http.Request) string {
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	trustedProxies := c.trustedProxyPrefixes
+	if trustedProxies == nil && len(c.TrustedProxies) > 0 {
+		trustedProxies, _ = ParseTrustedProxies(c.TrustedProxies)
+	}
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(trustedProxies, remote) {
+		return host
+	}
+
+	chain, ok := getForwardedChain(r, c.GetClientIPHeader())
+	if !ok {
+		return host
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, err := parseForwardedAddr(chain[i])
+		if err != nil {
+			break
+		}
+		client = addr
+		if !isTrustedProxy(trustedProxies, addr) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		prefix, err := parseIPPrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy \"%s\": %w", value, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// parseIPPrefix accepts an IP or a CIDR, with IPv4-mapped IPv6 values turned into IPv4.
+func parseIPPrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("expected an IP or CIDR")
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func isTrustedProxy(trustedProxies []netip.Prefix, addr netip.Addr) bool {
+	return containsIP(trustedProxies, addr.Unmap())
+}
+
+func getForwardedChain(r *http.Request, header string) ([]string, bool) {
+	values := r.Header.Values(header)
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	chain := []string{}
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			element = strings.TrimSpace(element)
+			if strings.EqualFold(header, HeaderForwarded) {
+				element = getForwardedFor(element)
+			}
+			chain = append(chain, element)
+		}
+	}
+
+	return chain, true
+}
+
+// getForwardedFor returns the "for" parameter of a RFC 7239 forwarded-element, or "" when it is missing.
+func getForwardedFor(element string) string {
+	for _, pair := range strings.Split(element, ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && strings.EqualFold(name, "for") {
+			return strings.Trim(value, "\"")
+		}
+	}
+	return ""
+}
+
+func parseForwardedAddr(value string) (netip.Addr, error) {
+	if strings.HasPrefix(value, "[") {
+		end := strings.Index(value, "]")
+		if end < 0 {
+			return netip.Addr{}, fmt.Errorf("invalid forwarded address \"%s\"", value)
+		}
+		return netip.ParseAddr(value[1:end])
+	}
+
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr, nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addrPort.Addr(), nil
+}
+
+// NormalizeIP canonicalizes IPv4-mapped IPv6 addresses and, when a prefix length is configured,
+// replaces the address by its network, like "2001:db8:1:2::/64", so the whole network shares one key.
+func (r *RateConfig) NormalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap().WithZone("")
+	if r == nil {
+		return addr.String()
+	}
+
+	bits := r.IPv6PrefixLength
+	if addr.Is4() {
+		bits = r.IPv4PrefixLength
+	}
+	if bits <= 0 || bits >= addr.BitLen() {
+		return addr.String()
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
+
+type customIPPrefix struct {
+	prefix     netip.Prefix
+	rateConfig *RateConfig
+}
+
+// GetRateLimiterRateConfigForIP returns the CustomIPs entry with the longest prefix containing ip, or the IP configuration.
+func (c *LimiterConfig) GetRateLimiterRateConfigForIP(ip string) (*RateConfig, bool) {
+	customIPs := c.customIPPrefixes
+	if customIPs == nil && c.CustomIPs != nil && len(*c.CustomIPs) > 0 {
+		customIPs = parseCustomIPs(c)
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || len(customIPs) == 0 {
+		return c.IP, false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	for _, customIP := range customIPs {
+		if customIP.prefix.Contains(addr) {
+			return customIP.rateConfig, true
+		}
+	}
+	return c.IP, false
+}
+
+// parseCustomIPs sorts the CustomIPs entries from the longest to the shortest prefix, ignoring invalid ones.
+func parseCustomIPs(config *LimiterConfig) []*customIPPrefix {
+	customIPs := []*customIPPrefix{}
+	if config.CustomIPs == nil {
+		return customIPs
+	}
+
+	for key, rateConfig := range *config.CustomIPs {
+		prefix, err := parseIPPrefix(strings.TrimSpace(key))
+		if err != nil {
+			PrintfWD(config, "ignoring custom IP \"%s\": %s", key, err)
+			continue
+		}
+		customIPs = append(customIPs, &customIPPrefix{prefix: prefix, rateConfig: rateConfig})
+	}
+
+	sort.SliceStable(customIPs, func(i, j int) bool {
+		if customIPs[i].prefix.Bits() != customIPs[j].prefix.Bits() {
+			return customIPs[i].prefix.Bits() > customIPs[j].prefix.Bits()
+		}
+		return customIPs[i].prefix.String() < customIPs[j].prefix.String()
+	})
+	return customIPs
+}
@@ -0,0 +1,173 @@
+package ratelimiter
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientIPTestSuite struct {
+	suite.Suite
+}
+
+func TestClientIPTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientIPTestSuite))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_NoTrustedProxies() {
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(HeaderXForwardedFor, "203.0.113.7")
+
+	assert.Equal(s.T(), "10.0.0.1", (&LimiterConfig{}).GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_UntrustedRemote() {
+	config := &LimiterConfig{TrustedProxies: []string{"10.0.0.0/8"}}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "198.51.100.9:1234"
+	request.Header.Set(HeaderXForwardedFor, "203.0.113.7")
+
+	assert.Equal(s.T(), "198.51.100.9", config.GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_XForwardedFor() {
+	config := &LimiterConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Add(HeaderXForwardedFor, "1.1.1.1, 203.0.113.7")
+	request.Header.Add(HeaderXForwardedFor, "192.168.1.1, 10.0.0.2")
+
+	assert.Equal(s.T(), "203.0.113.7", config.GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_AllTrusted() {
+	config := &LimiterConfig{TrustedProxies: []string{"10.0.0.0/8"}}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(HeaderXForwardedFor, "10.0.0.3, 10.0.0.2")
+
+	assert.Equal(s.T(), "10.0.0.3", config.GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_InvalidEntry() {
+	config := &LimiterConfig{TrustedProxies: []string{"10.0.0.0/8"}}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(HeaderXForwardedFor, "203.0.113.7, garbage, 10.0.0.2")
+
+	assert.Equal(s.T(), "10.0.0.2", config.GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_Forwarded() {
+	config := &LimiterConfig{TrustedProxies: []string{"10.0.0.0/8", "2001:db8:ffff::/48"}, ClientIPHeader: HeaderForwarded}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "[2001:db8:ffff::1]:443"
+	request.Header.Set(HeaderForwarded, `for=192.0.2.60;proto=http, For="[2001:db8:cafe::17]:4711", for=10.0.0.2;by=10.0.0.1`)
+	request.Header.Set(HeaderXForwardedFor, "198.51.100.1")
+
+	assert.Equal(s.T(), "2001:db8:cafe::17", config.GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_IgnoresOtherHeaders() {
+	config := &LimiterConfig{TrustedProxies: []string{"10.0.0.0/8"}}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(HeaderForwarded, "for=198.51.100.1")
+	request.Header.Set(HeaderXRealIP, "198.51.100.2")
+
+	assert.Equal(s.T(), "10.0.0.1", config.GetClientIP(request))
+
+	request.Header.Set(HeaderXForwardedFor, "203.0.113.7")
+	assert.Equal(s.T(), "203.0.113.7", config.GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestGetClientIP_XRealIP() {
+	config := &LimiterConfig{TrustedProxies: []string{"10.0.0.1"}, ClientIPHeader: HeaderXRealIP}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(HeaderXForwardedFor, "198.51.100.1")
+	request.Header.Set(HeaderXRealIP, "203.0.113.7")
+
+	assert.Equal(s.T(), "203.0.113.7", config.GetClientIP(request))
+}
+
+func (s *ClientIPTestSuite) TestParseTrustedProxies() {
+	prefixes, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.1", "::ffff:172.16.0.0/108", "2001:db8::1"})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"10.0.0.0/8", "192.168.1.1/32", "172.16.0.0/12", "2001:db8::1/128"}, []string{
+		prefixes[0].String(), prefixes[1].String(), prefixes[2].String(), prefixes[3].String(),
+	})
+
+	prefixes, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), prefixes)
+
+	prefixes, err = ParseTrustedProxies([]string{"proxy.local"})
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), prefixes)
+}
+
+func (s *ClientIPTestSuite) TestNormalizeIP() {
+	config := &RateConfig{IPv4PrefixLength: 24, IPv6PrefixLength: 64}
+
+	assert.Equal(s.T(), "203.0.113.0/24", config.NormalizeIP("203.0.113.7"))
+	assert.Equal(s.T(), "203.0.113.0/24", config.NormalizeIP("::ffff:203.0.113.7"))
+	assert.Equal(s.T(), "2001:db8:1:2::/64", config.NormalizeIP("2001:db8:1:2:aaaa:bbbb:cccc:dddd"))
+	assert.Equal(s.T(), "invalid", config.NormalizeIP("invalid"))
+	assert.Equal(s.T(), "", config.NormalizeIP(""))
+}
+
+func (s *ClientIPTestSuite) TestNormalizeIP_FullLength() {
+	config := &RateConfig{}
+
+	assert.Equal(s.T(), "203.0.113.7", config.NormalizeIP("::ffff:203.0.113.7"))
+	assert.Equal(s.T(), "2001:db8::1", config.NormalizeIP("2001:0db8:0000::0001"))
+	assert.Equal(s.T(), "203.0.113.7", (&RateConfig{IPv4PrefixLength: 32}).NormalizeIP("203.0.113.7"))
+	assert.Equal(s.T(), "203.0.113.7", (*RateConfig)(nil).NormalizeIP("203.0.113.7"))
+}
+
+func (s *ClientIPTestSuite) TestGetRateLimiterRateConfigForIP() {
+	ipConfig := &RateConfig{MaxRequestsPerSecond: 10}
+	networkConfig := &RateConfig{MaxRequestsPerSecond: 100}
+	subnetConfig := &RateConfig{MaxRequestsPerSecond: 200}
+	hostConfig := &RateConfig{MaxRequestsPerSecond: 300}
+	ipv6Config := &RateConfig{MaxRequestsPerSecond: 400}
+	config := &LimiterConfig{
+		IP: ipConfig,
+		CustomIPs: &map[string]*RateConfig{
+			"10.0.0.0/8":      networkConfig,
+			"10.1.0.0/16":     subnetConfig,
+			"10.1.2.3":        hostConfig,
+			"2001:db8::/32":   ipv6Config,
+			"not-an-ip/oops":  hostConfig,
+			"::ffff:10.9.9.9": hostConfig,
+		},
+	}
+
+	rateConfig, ok := config.GetRateLimiterRateConfigForIP("10.1.2.3")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), hostConfig, rateConfig)
+
+	rateConfig, _ = config.GetRateLimiterRateConfigForIP("10.1.9.9")
+	assert.Equal(s.T(), subnetConfig, rateConfig)
+
+	rateConfig, _ = config.GetRateLimiterRateConfigForIP("::ffff:10.2.0.1")
+	assert.Equal(s.T(), networkConfig, rateConfig)
+
+	rateConfig, _ = config.GetRateLimiterRateConfigForIP("10.9.9.9")
+	assert.Equal(s.T(), hostConfig, rateConfig)
+
+	rateConfig, _ = config.GetRateLimiterRateConfigForIP("2001:db8::1")
+	assert.Equal(s.T(), ipv6Config, rateConfig)
+
+	rateConfig, ok = config.GetRateLimiterRateConfigForIP("192.0.2.1")
+	assert.False(s.T(), ok)
+	assert.Equal(s.T(), ipConfig, rateConfig)
+
+	rateConfig, ok = config.GetRateLimiterRateConfigForIP("")
+	assert.False(s.T(), ok)
+	assert.Equal(s.T(), ipConfig, rateConfig)
+}
@@ -2,7 +2,10 @@ package ratelimiter
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/netip"
 	"os"
 	"regexp"
 	"strings"
@@ -15,25 +18,199 @@ const envKeyIPMaxRequestsPerSecond = "MAX_REQUESTS_RATE_LIMITER_IP"
 const envKeyIPBlockTimeMilliseconds = "BLOCK_TIME_RATE_LIMITER_IP"
 const envKeyTokenMaxRequestsPerSecond = "MAX_REQUESTS_RATE_LIMITER_TOKEN"
 const envKeyTokenBlockTimeMilliseconds = "BLOCK_TIME_RATE_LIMITER_TOKEN"
+const envKeyIPWindowMilliseconds = "WINDOW_TIME_RATE_LIMITER_IP"
+const envKeyTokenWindowMilliseconds = "WINDOW_TIME_RATE_LIMITER_TOKEN"
+const envKeyIPLimits = "LIMITS_RATE_LIMITER_IP"
+const envKeyTokenLimits = "LIMITS_RATE_LIMITER_TOKEN"
+const envKeyIPAlgorithm = "ALGORITHM_RATE_LIMITER_IP"
+const envKeyIPBucketCapacity = "BUCKET_CAPACITY_RATE_LIMITER_IP"
+const envKeyIPRefillRatePerSecond = "REFILL_RATE_RATE_LIMITER_IP"
+const envKeyIPv4PrefixLength = "IPV4_PREFIX_RATE_LIMITER_IP"
+const envKeyIPv6PrefixLength = "IPV6_PREFIX_RATE_LIMITER_IP"
+const envKeyTokenAlgorithm = "ALGORITHM_RATE_LIMITER_TOKEN"
+const envKeyTokenBucketCapacity = "BUCKET_CAPACITY_RATE_LIMITER_TOKEN"
+const envKeyTokenRefillRatePerSecond = "REFILL_RATE_RATE_LIMITER_TOKEN"
 const envKeyDebug = "DEBUG_RATE_LIMITER"
+const envKeyHeadersMode = "HEADERS_RATE_LIMITER"
+const envKeyMessagesFile = "MESSAGES_FILE_RATE_LIMITER"
+const envKeyTokenSources = "TOKEN_SOURCES_RATE_LIMITER"
+const envKeyTrustedProxies = "TRUSTED_PROXIES_RATE_LIMITER"
+const envKeyClientIPHeader = "CLIENT_IP_HEADER_RATE_LIMITER"
+const envKeyAllowedIPs = "ALLOWED_IPS_RATE_LIMITER"
+const envKeyDeniedIPs = "DENIED_IPS_RATE_LIMITER"
+const envKeyAllowedTokens = "ALLOWED_TOKENS_RATE_LIMITER"
+const envKeyDeniedTokens = "DENIED_TOKENS_RATE_LIMITER"
+const envKeyUnknownTokenPolicy = "UNKNOWN_TOKEN_POLICY_RATE_LIMITER"
+const envKeyTokenHashSecret = "TOKEN_HASH_SECRET_RATE_LIMITER"
+const envKeyEvaluationPolicy = "EVALUATION_POLICY_RATE_LIMITER"
+const envKeyRoutesFile = "ROUTES_FILE_RATE_LIMITER"
+const envKeyRulesFile = "RULES_FILE_RATE_LIMITER"
+const envKeyRuleMode = "RULE_MODE_RATE_LIMITER"
+const envKeyConfigFile = "RATE_LIMITER_CONFIG_FILE"
 const envUseRedis = "USE_RATE_LIMITER_REDIS"
 const envRedisAddress = "ADDRESS_RATE_LIMITER_REDIS"
 const envRedisPassword = "PASSWORD_RATE_LIMITER_REDIS"
 const envRedisDB = "DB_RATE_LIMITER_REDIS"
 
+const AlgorithmSlidingLog = adapters.AlgorithmSlidingLog
+const AlgorithmTokenBucket = adapters.AlgorithmTokenBucket
+const AlgorithmGCRA = adapters.AlgorithmGCRA
+const AlgorithmFixedWindow = adapters.AlgorithmFixedWindow
+const AlgorithmSlidingWindowCounter = adapters.AlgorithmSlidingWindowCounter
+
+const HeadersModeIETF = response_writer.HeadersModeIETF
+const HeadersModeLegacy = response_writer.HeadersModeLegacy
+const HeadersModeNone = response_writer.HeadersModeNone
+
+const defaultWindowMilliseconds = 1000
+
+type WindowLimit struct {
+	MaxRequests        int64 `json:"maxRequests"`
+	WindowMilliseconds int64 `json:"windowMilliseconds"`
+}
+
 type RateConfig struct {
-	MaxRequestsPerSecond  int64 `json:"maxRequestsPerSecond"`
-	BlockTimeMilliseconds int64 `json:"blockTimeMilliseconds"`
+	// MaxRequestsPerSecond is the number of requests allowed within WindowMilliseconds, one second when not set.
+	MaxRequestsPerSecond  int64  `json:"maxRequestsPerSecond"`
+	BlockTimeMilliseconds int64  `json:"blockTimeMilliseconds"`
+	WindowMilliseconds    int64  `json:"windowMilliseconds"`
+	Algorithm             string `json:"algorithm"`
+	BucketCapacity        int64  `json:"bucketCapacity"`
+	RefillRatePerSecond   int64  `json:"refillRatePerSecond"`
+	// Limits, when set, replaces MaxRequestsPerSecond and WindowMilliseconds with several windows checked together.
+	Limits []*WindowLimit `json:"limits"`
+	// IPv4PrefixLength and IPv6PrefixLength group IP keys by network, e.g. 24 and 64. Zero keeps the full address.
+	IPv4PrefixLength int `json:"ipv4PrefixLength"`
+	IPv6PrefixLength int `json:"ipv6PrefixLength"`
+}
+
+func (r *RateConfig) GetAlgorithm() string {
+	if r.Algorithm == "" {
+		return AlgorithmSlidingLog
+	}
+	return r.Algorithm
+}
+
+func (r *RateConfig) GetWindowMilliseconds() int64 {
+	if r.WindowMilliseconds > 0 {
+		return r.WindowMilliseconds
+	}
+	return defaultWindowMilliseconds
+}
+
+func (r *RateConfig) GetLimits() []*WindowLimit {
+	if len(r.Limits) > 0 {
+		return r.Limits
+	}
+	return []*WindowLimit{{MaxRequests: r.MaxRequestsPerSecond, WindowMilliseconds: r.GetWindowMilliseconds()}}
+}
+
+// Validate rejects the limits the algorithms cannot divide by: windows that are not positive and, for GCRA, no requests.
+// It also rejects the buckets that would deny every request: no capacity or, for the token bucket, no refill.
+func (r *RateConfig) Validate() error {
+	if r == nil {
+		return errors.New("no rate config")
+	}
+	for _, limit := range r.GetLimits() {
+		if limit == nil {
+			return errors.New("empty window limit")
+		}
+		if limit.WindowMilliseconds <= 0 {
+			return fmt.Errorf("invalid window limit \"%s\": window must be positive", FormatWindowLimits([]*WindowLimit{limit}))
+		}
+		if r.GetAlgorithm() == AlgorithmGCRA && limit.MaxRequests <= 0 {
+			return fmt.Errorf("invalid window limit \"%s\": %s needs a positive number of requests", FormatWindowLimits([]*WindowLimit{limit}), AlgorithmGCRA)
+		}
+	}
+
+	switch r.GetAlgorithm() {
+	case AlgorithmTokenBucket, AlgorithmGCRA:
+		if r.GetBucketCapacity() <= 0 {
+			return fmt.Errorf("%s needs a positive bucket capacity", r.GetAlgorithm())
+		}
+	}
+	if r.GetAlgorithm() == AlgorithmTokenBucket && r.GetRefillRatePerSecond() <= 0 {
+		return fmt.Errorf("%s needs a positive refill rate per second", AlgorithmTokenBucket)
+	}
+	return nil
+}
+
+// GetBucketCapacity defaults to the requests of the first window, so a config written with Limits gets the same burst.
+func (r *RateConfig) GetBucketCapacity() int64 {
+	if r.BucketCapacity > 0 {
+		return r.BucketCapacity
+	}
+	limit := r.GetLimits()[0]
+	if limit == nil {
+		return 0
+	}
+	return limit.MaxRequests
+}
+
+// GetRefillRatePerSecond defaults to the rate of the first window, rounded down, e.g. 5 for 300 requests per minute.
+func (r *RateConfig) GetRefillRatePerSecond() int64 {
+	if r.RefillRatePerSecond > 0 {
+		return r.RefillRatePerSecond
+	}
+	limit := r.GetLimits()[0]
+	if limit == nil || limit.WindowMilliseconds <= 0 {
+		return 0
+	}
+	return limit.MaxRequests * 1000 / limit.WindowMilliseconds
 }
 
 type LimiterConfig struct {
-	IP             *RateConfig                               `json:"ip"`
-	Token          *RateConfig                               `json:"token"`
-	CustomTokens   *map[string]*RateConfig                   `json:"tokens"`
-	StorageAdapter adapters.RateLimitStorageAdapter          `json:"-"`
-	ResponseWriter response_writer.RateLimiterResponseWriter `json:"-"`
-	Debug          bool                                      `json:"debug"`
-	DisableEnvs    bool                                      `json:"disableEnvs"`
+	IP                    *RateConfig                                      `json:"ip"`
+	Token                 *RateConfig                                      `json:"token"`
+	CustomTokens          *map[string]*RateConfig                          `json:"tokens"`
+	CustomIPs             *map[string]*RateConfig                          `json:"ips"`
+	StorageAdapter        adapters.RateLimitStorageAdapter                 `json:"-"`
+	ResponseWriter        response_writer.RateLimiterResponseWriter        `json:"-"`
+	Debug                 bool                                             `json:"debug"`
+	DisableEnvs           bool                                             `json:"disableEnvs"`
+	HeadersMode           string                                           `json:"headersMode"`
+	ContentNegotiator     *response_writer.RateLimiterContentNegotiator    `json:"-"`
+	RequestResponseWriter response_writer.RateLimiterRequestResponseWriter `json:"-"`
+	TokenSources          []*TokenSource                                   `json:"tokenSources"`
+	KeyExtractors         []KeyExtractor                                   `json:"-"`
+	TrustedProxies        []string                                         `json:"trustedProxies"`
+	ClientIPHeader        string                                           `json:"clientIPHeader"`
+	AllowedIPs            []string                                         `json:"allowedIPs"`
+	DeniedIPs             []string                                         `json:"deniedIPs"`
+	AllowedTokens         []string                                         `json:"allowedTokens"`
+	DeniedTokens          []string                                         `json:"deniedTokens"`
+	UnknownTokenPolicy    string                                           `json:"unknownTokenPolicy"`
+	TokenRegistry         TokenRegistry                                    `json:"-"`
+	TokenHashSecret       string                                           `json:"-"`
+	EvaluationPolicy      string                                           `json:"evaluationPolicy"`
+	Routes                []*RouteRule                                     `json:"routes"`
+	RuleMode              string                                           `json:"ruleMode"`
+	Rules                 []*Rule                                          `json:"rules"`
+	TokenPlanProvider     TokenPlanProvider                                `json:"-"`
+	sources               map[string]string
+	fileRateConfigs       map[string]json.RawMessage
+	trustedProxyPrefixes  []netip.Prefix
+	customIPPrefixes      []*customIPPrefix
+	accessLists           *accessLists
+}
+
+func (c *LimiterConfig) GetHeadersMode() string {
+	if c.HeadersMode == "" {
+		return HeadersModeIETF
+	}
+	return c.HeadersMode
+}
+
+func (c *LimiterConfig) GetRequestResponseWriter(r *http.Request) response_writer.RateLimiterRequestResponseWriter {
+	if c.ContentNegotiator != nil {
+		if responseWriter := c.ContentNegotiator.Select(r.Header.Get("Accept")); responseWriter != nil {
+			return response_writer.NewRateLimiterRequestResponseWriter(responseWriter)
+		}
+	}
+	if c.RequestResponseWriter != nil {
+		return c.RequestResponseWriter
+	}
+	return response_writer.NewRateLimiterRequestResponseWriter(c.ResponseWriter)
 }
 
 func (c *LimiterConfig) GetRateLimiterRateConfigForToken(token string) (*RateConfig, bool) {
@@ -46,6 +223,8 @@ func (c *LimiterConfig) GetRateLimiterRateConfigForToken(token string) (*RateCon
 }
 
 func getDefaultConfiguration() *LimiterConfig {
+	contentNegotiator, _ := response_writer.NewRateLimiterContentNegotiator(nil)
+
 	return &LimiterConfig{
 		IP: &RateConfig{
 			MaxRequestsPerSecond:  100,
@@ -55,10 +234,12 @@ func getDefaultConfiguration() *LimiterConfig {
 			MaxRequestsPerSecond:  200,
 			BlockTimeMilliseconds: 500,
 		},
-		CustomTokens:   &map[string]*RateConfig{},
-		StorageAdapter: adapters.NewRateLimitMemoryStorageAdapter(),
-		ResponseWriter: response_writer.NewRateLimiterDefaultResponseWriter(),
-		Debug:          false,
+		CustomTokens:      &map[string]*RateConfig{},
+		CustomIPs:         &map[string]*RateConfig{},
+		StorageAdapter:    adapters.NewRateLimitMemoryStorageAdapter(),
+		ResponseWriter:    response_writer.NewRateLimiterDefaultResponseWriter(),
+		ContentNegotiator: contentNegotiator,
+		Debug:             false,
 	}
 }
 
@@ -67,27 +248,48 @@ func SetConfiguration(config *LimiterConfig) *LimiterConfig {
 
 	if config == nil {
 		config = defaultConfiguration
+		config.sources = map[string]string{}
+	} else {
+		configureCodeSources(config)
 	}
 
+	configureConfigFile(config)
+
 	if !config.DisableEnvs {
 		debug, ok := GetEnvBoolean(envKeyDebug)
-		if ok {
+		if ok && config.useEnv(envKeyDebug) {
 			config.Debug = debug
 			PrintfWD(config, "using env %s", envKeyDebug)
 		}
 	}
 
+	configureTokenHashSecret(config)
 	configureIP(config, defaultConfiguration)
 	configureToken(config, defaultConfiguration)
 	configureCustomTokens(config, defaultConfiguration)
+	configureCustomIPs(config, defaultConfiguration)
 	configureStorageAdapter(config, defaultConfiguration)
+	configureLimits(config)
+	warnUnhashedTokens(config)
 	configureResponseWriter(config, defaultConfiguration)
+	configureHeadersMode(config)
+	configureTokenSources(config)
+	configureUnknownTokenPolicy(config)
+	configureEvaluationPolicy(config)
+	configureRoutes(config)
+	configureRules(config)
+	configureClientIP(config)
+	configureAccessLists(config)
 
 	if config.Debug {
-		jsonConfiguration, err := json.Marshal(config)
+		jsonConfiguration, err := json.Marshal(config.redacted())
 		if err == nil {
 			PrintfWD(config, "using configuration: %s", jsonConfiguration)
 		}
+		jsonSources, err := json.Marshal(config.GetConfigSources())
+		if err == nil {
+			PrintfWD(config, "using configuration sources: %s", jsonSources)
+		}
 	}
 
 	return config
@@ -100,16 +302,58 @@ func configureIP(config *LimiterConfig, defaultConfiguration *LimiterConfig) {
 
 	if !config.DisableEnvs {
 		mrps, ok := GetEnvLargeint(envKeyIPMaxRequestsPerSecond)
-		if ok {
+		if ok && config.useEnv(envKeyIPMaxRequestsPerSecond) {
 			config.IP.MaxRequestsPerSecond = mrps
 			PrintfWD(config, "using env %s", envKeyIPMaxRequestsPerSecond)
 		}
 
 		bt, ok := GetEnvLargeint(envKeyIPBlockTimeMilliseconds)
-		if ok {
+		if ok && config.useEnv(envKeyIPBlockTimeMilliseconds) {
 			config.IP.BlockTimeMilliseconds = bt
 			PrintfWD(config, "using env %s", envKeyIPBlockTimeMilliseconds)
 		}
+
+		window, ok := GetEnvLargeint(envKeyIPWindowMilliseconds)
+		if ok && config.useEnv(envKeyIPWindowMilliseconds) {
+			config.IP.WindowMilliseconds = window
+			PrintfWD(config, "using env %s", envKeyIPWindowMilliseconds)
+		}
+
+		limits, ok := getEnvWindowLimits(envKeyIPLimits, envKeyIPLimits)
+		if ok && config.useEnv(envKeyIPLimits) {
+			config.IP.Limits = limits
+			PrintfWD(config, "using env %s", envKeyIPLimits)
+		}
+
+		algorithm, ok := GetEnvString(envKeyIPAlgorithm)
+		if ok && config.useEnv(envKeyIPAlgorithm) {
+			config.IP.Algorithm = algorithm
+			PrintfWD(config, "using env %s", envKeyIPAlgorithm)
+		}
+
+		capacity, ok := GetEnvLargeint(envKeyIPBucketCapacity)
+		if ok && config.useEnv(envKeyIPBucketCapacity) {
+			config.IP.BucketCapacity = capacity
+			PrintfWD(config, "using env %s", envKeyIPBucketCapacity)
+		}
+
+		refillRate, ok := GetEnvLargeint(envKeyIPRefillRatePerSecond)
+		if ok && config.useEnv(envKeyIPRefillRatePerSecond) {
+			config.IP.RefillRatePerSecond = refillRate
+			PrintfWD(config, "using env %s", envKeyIPRefillRatePerSecond)
+		}
+
+		ipv4PrefixLength, ok := GetEnvLargeint(envKeyIPv4PrefixLength)
+		if ok && config.useEnv(envKeyIPv4PrefixLength) {
+			config.IP.IPv4PrefixLength = int(ipv4PrefixLength)
+			PrintfWD(config, "using env %s", envKeyIPv4PrefixLength)
+		}
+
+		ipv6PrefixLength, ok := GetEnvLargeint(envKeyIPv6PrefixLength)
+		if ok && config.useEnv(envKeyIPv6PrefixLength) {
+			config.IP.IPv6PrefixLength = int(ipv6PrefixLength)
+			PrintfWD(config, "using env %s", envKeyIPv6PrefixLength)
+		}
 	}
 }
 
@@ -120,16 +364,46 @@ func configureToken(config *LimiterConfig, defaultConfiguration *LimiterConfig)
 
 	if !config.DisableEnvs {
 		mrps, ok := GetEnvLargeint(envKeyTokenMaxRequestsPerSecond)
-		if ok {
+		if ok && config.useEnv(envKeyTokenMaxRequestsPerSecond) {
 			config.Token.MaxRequestsPerSecond = mrps
 			PrintfWD(config, "using env %s", envKeyTokenMaxRequestsPerSecond)
 		}
 
 		bt, ok := GetEnvLargeint(envKeyTokenBlockTimeMilliseconds)
-		if ok {
+		if ok && config.useEnv(envKeyTokenBlockTimeMilliseconds) {
 			config.Token.BlockTimeMilliseconds = bt
 			PrintfWD(config, "using env %s", envKeyTokenBlockTimeMilliseconds)
 		}
+
+		window, ok := GetEnvLargeint(envKeyTokenWindowMilliseconds)
+		if ok && config.useEnv(envKeyTokenWindowMilliseconds) {
+			config.Token.WindowMilliseconds = window
+			PrintfWD(config, "using env %s", envKeyTokenWindowMilliseconds)
+		}
+
+		limits, ok := getEnvWindowLimits(envKeyTokenLimits, envKeyTokenLimits)
+		if ok && config.useEnv(envKeyTokenLimits) {
+			config.Token.Limits = limits
+			PrintfWD(config, "using env %s", envKeyTokenLimits)
+		}
+
+		algorithm, ok := GetEnvString(envKeyTokenAlgorithm)
+		if ok && config.useEnv(envKeyTokenAlgorithm) {
+			config.Token.Algorithm = algorithm
+			PrintfWD(config, "using env %s", envKeyTokenAlgorithm)
+		}
+
+		capacity, ok := GetEnvLargeint(envKeyTokenBucketCapacity)
+		if ok && config.useEnv(envKeyTokenBucketCapacity) {
+			config.Token.BucketCapacity = capacity
+			PrintfWD(config, "using env %s", envKeyTokenBucketCapacity)
+		}
+
+		refillRate, ok := GetEnvLargeint(envKeyTokenRefillRatePerSecond)
+		if ok && config.useEnv(envKeyTokenRefillRatePerSecond) {
+			config.Token.RefillRatePerSecond = refillRate
+			PrintfWD(config, "using env %s", envKeyTokenRefillRatePerSecond)
+		}
 	}
 }
 
@@ -139,6 +413,10 @@ func configureCustomTokens(config *LimiterConfig, defaultConfiguration *LimiterC
 	}
 
 	for key := range *config.CustomTokens {
+		if rateConfig, ok := config.getFileRateConfig("tokens."+key, config.Token); ok {
+			(*config.CustomTokens)[key] = rateConfig
+			continue
+		}
 		value, ok := (*config.CustomTokens)[key]
 		if !ok || value == nil {
 			(*config.CustomTokens)[key] = config.Token
@@ -147,55 +425,164 @@ func configureCustomTokens(config *LimiterConfig, defaultConfiguration *LimiterC
 
 	customTokens := getCustomTokenList()
 	for _, customToken := range *customTokens {
+		if config.isFromFile("tokens." + customToken) {
+			PrintfWD(config, "ignoring envs of custom token \"%s\": set by the config file", config.HashToken(customToken))
+			continue
+		}
+		config.setSource("tokens."+customToken, ConfigSourceEnv)
 		configureCustomToken(config, defaultConfiguration, customToken)
 	}
 }
 
 func getCustomTokenList() *[]string {
-	envKeyRegex := regexp.MustCompile("^RATE_LIMITER_TOKEN_(.*)_(MAX_REQUESTS|BLOCK_TIME)$")
+	return getCustomEnvNames("RATE_LIMITER_TOKEN", "MAX_REQUESTS|BLOCK_TIME|WINDOW_TIME|LIMITS|ALGORITHM|BUCKET_CAPACITY|REFILL_RATE")
+}
+
+func getCustomEnvNames(envKeyPrefix string, envKeySuffixes string) *[]string {
+	envKeyRegex := regexp.MustCompile(fmt.Sprintf("^%s_(.*)_(%s)$", envKeyPrefix, envKeySuffixes))
 
-	foundTokens := map[string]bool{}
+	foundNames := map[string]bool{}
 
 	envs := os.Environ()
 	for _, env := range envs {
 		envPair := strings.SplitN(env, "=", 2)
 		envKey := envPair[0]
 		if envKeyRegex.Match([]byte(envKey)) {
-			foundTokens[envKeyRegex.FindStringSubmatch(envKey)[1]] = true
+			foundNames[envKeyRegex.FindStringSubmatch(envKey)[1]] = true
 		}
 	}
 
-	tokens := []string{}
-	for k := range foundTokens {
-		tokens = append(tokens, k)
+	names := []string{}
+	for k := range foundNames {
+		names = append(names, k)
 	}
 
-	return &tokens
+	return &names
 }
 
 func configureCustomToken(config *LimiterConfig, defaultConfiguration *LimiterConfig, customToken string) {
 
-	PrintfWD(config, "configuring custom token \"%s\"", customToken)
+	PrintfWD(config, "configuring custom token \"%s\"", config.HashToken(customToken))
 
-	maxRequestsPerSecondEnvKey := fmt.Sprintf("RATE_LIMITER_TOKEN_%s_MAX_REQUESTS", customToken)
+	(*config.CustomTokens)[customToken] = getCustomRateConfig(config, "RATE_LIMITER_TOKEN", customToken, config.HashToken(customToken), config.Token)
+}
+
+func configureCustomIPs(config *LimiterConfig, defaultConfiguration *LimiterConfig) {
+	if config.CustomIPs == nil {
+		config.CustomIPs = defaultConfiguration.CustomIPs
+	}
+
+	for key := range *config.CustomIPs {
+		if rateConfig, ok := config.getFileRateConfig("ips."+key, config.IP); ok {
+			(*config.CustomIPs)[key] = rateConfig
+			continue
+		}
+		value, ok := (*config.CustomIPs)[key]
+		if !ok || value == nil {
+			(*config.CustomIPs)[key] = config.IP
+		}
+	}
+
+	if !config.DisableEnvs {
+		customIPs := getCustomIPList()
+		for _, customIP := range *customIPs {
+			configureCustomIP(config, customIP)
+		}
+	}
+
+	config.customIPPrefixes = parseCustomIPs(config)
+}
+
+func getCustomIPList() *[]string {
+	return getCustomEnvNames("RATE_LIMITER_IP", "CIDR|MAX_REQUESTS|BLOCK_TIME|WINDOW_TIME|LIMITS|ALGORITHM|BUCKET_CAPACITY|REFILL_RATE")
+}
+
+func configureCustomIP(config *LimiterConfig, customIP string) {
+	cidrEnvKey := fmt.Sprintf("RATE_LIMITER_IP_%s_CIDR", customIP)
+	cidr, ok := GetEnvString(cidrEnvKey)
+	if !ok {
+		PrintfWD(config, "env \"%s\" not found: ignoring custom IP \"%s\"", cidrEnvKey, customIP)
+		return
+	}
+
+	if !config.useEnvForPath(cidrEnvKey, "ips."+cidr) {
+		return
+	}
+
+	PrintfWD(config, "configuring custom IP \"%s\" for %s", customIP, cidr)
+
+	rateConfig := getCustomRateConfig(config, "RATE_LIMITER_IP", customIP, customIP, config.IP)
+	rateConfig.IPv4PrefixLength = config.IP.IPv4PrefixLength
+	rateConfig.IPv6PrefixLength = config.IP.IPv6PrefixLength
+	(*config.CustomIPs)[cidr] = rateConfig
+}
+
+// getCustomRateConfig reads the envs of a custom token or IP, logging logName in place of name, so tokens are not logged.
+func getCustomRateConfig(config *LimiterConfig, envKeyPrefix string, name string, logName string, defaultRateConfig *RateConfig) *RateConfig {
+	maxRequestsPerSecondEnvKey := fmt.Sprintf("%s_%s_MAX_REQUESTS", envKeyPrefix, name)
 	maxRequestsPerSecond, ok := GetEnvLargeint(maxRequestsPerSecondEnvKey)
 	if !ok {
-		defaultValue := config.Token.MaxRequestsPerSecond
-		PrintfWD(config, "env \"%s\" not found: using default value %d", maxRequestsPerSecondEnvKey, defaultValue)
+		defaultValue := defaultRateConfig.MaxRequestsPerSecond
+		PrintfWD(config, "env \"%s\" not found: using default value %d", fmt.Sprintf("%s_%s_MAX_REQUESTS", envKeyPrefix, logName), defaultValue)
 		maxRequestsPerSecond = defaultValue
 	}
 
-	blockTimeMillisecondEnvKey := fmt.Sprintf("RATE_LIMITER_TOKEN_%s_BLOCK_TIME", customToken)
+	blockTimeMillisecondEnvKey := fmt.Sprintf("%s_%s_BLOCK_TIME", envKeyPrefix, name)
 	blockTimeMilliseconds, ok := GetEnvLargeint(blockTimeMillisecondEnvKey)
 	if !ok {
-		defaultValue := config.Token.BlockTimeMilliseconds
-		PrintfWD(config, "env \"%s\" not found: using default value %d", blockTimeMillisecondEnvKey, defaultValue)
+		defaultValue := defaultRateConfig.BlockTimeMilliseconds
+		PrintfWD(config, "env \"%s\" not found: using default value %d", fmt.Sprintf("%s_%s_BLOCK_TIME", envKeyPrefix, logName), defaultValue)
 		blockTimeMilliseconds = defaultValue
 	}
 
-	(*config.CustomTokens)[customToken] = &RateConfig{
+	windowMillisecondsEnvKey := fmt.Sprintf("%s_%s_WINDOW_TIME", envKeyPrefix, name)
+	windowMilliseconds, ok := GetEnvLargeint(windowMillisecondsEnvKey)
+	if !ok {
+		defaultValue := defaultRateConfig.WindowMilliseconds
+		PrintfWD(config, "env \"%s\" not found: using default value %d", fmt.Sprintf("%s_%s_WINDOW_TIME", envKeyPrefix, logName), defaultValue)
+		windowMilliseconds = defaultValue
+	}
+
+	limitsEnvKey := fmt.Sprintf("%s_%s_LIMITS", envKeyPrefix, name)
+	limits, ok := getEnvWindowLimits(limitsEnvKey, fmt.Sprintf("%s_%s_LIMITS", envKeyPrefix, logName))
+	if !ok {
+		defaultValue := defaultRateConfig.Limits
+		PrintfWD(config, "env \"%s\" not found: using default value %s", fmt.Sprintf("%s_%s_LIMITS", envKeyPrefix, logName), FormatWindowLimits(defaultValue))
+		limits = defaultValue
+	}
+
+	algorithmEnvKey := fmt.Sprintf("%s_%s_ALGORITHM", envKeyPrefix, name)
+	algorithm, ok := GetEnvString(algorithmEnvKey)
+	if !ok {
+		defaultValue := defaultRateConfig.Algorithm
+		PrintfWD(config, "env \"%s\" not found: using default value \"%s\"", fmt.Sprintf("%s_%s_ALGORITHM", envKeyPrefix, logName), defaultValue)
+		algorithm = defaultValue
+	}
+
+	bucketCapacityEnvKey := fmt.Sprintf("%s_%s_BUCKET_CAPACITY", envKeyPrefix, name)
+	bucketCapacity, ok := GetEnvLargeint(bucketCapacityEnvKey)
+	if !ok {
+		defaultValue := defaultRateConfig.BucketCapacity
+		PrintfWD(config, "env \"%s\" not found: using default value %d", fmt.Sprintf("%s_%s_BUCKET_CAPACITY", envKeyPrefix, logName), defaultValue)
+		bucketCapacity = defaultValue
+	}
+
+	refillRateEnvKey := fmt.Sprintf("%s_%s_REFILL_RATE", envKeyPrefix, name)
+	refillRatePerSecond, ok := GetEnvLargeint(refillRateEnvKey)
+	if !ok {
+		defaultValue := defaultRateConfig.RefillRatePerSecond
+		PrintfWD(config, "env \"%s\" not found: using default value %d", fmt.Sprintf("%s_%s_REFILL_RATE", envKeyPrefix, logName), defaultValue)
+		refillRatePerSecond = defaultValue
+	}
+
+	return &RateConfig{
 		MaxRequestsPerSecond:  maxRequestsPerSecond,
 		BlockTimeMilliseconds: blockTimeMilliseconds,
+		WindowMilliseconds:    windowMilliseconds,
+		Limits:                limits,
+		Algorithm:             algorithm,
+		BucketCapacity:        bucketCapacity,
+		RefillRatePerSecond:   refillRatePerSecond,
 	}
 }
 
@@ -238,6 +625,31 @@ func configureRedisStorageAdapter(config *LimiterConfig) {
 func configureResponseWriter(config *LimiterConfig, defaultConfiguration *LimiterConfig) {
 	if config.ResponseWriter == nil {
 		config.ResponseWriter = defaultConfiguration.ResponseWriter
+		if config.ContentNegotiator == nil && config.RequestResponseWriter == nil {
+			config.ContentNegotiator = defaultConfiguration.ContentNegotiator
+		}
+	}
+
+	if config.ResponseWriter == defaultConfiguration.ResponseWriter && !config.DisableEnvs {
+		messagesFile, ok := GetEnvString(envKeyMessagesFile)
+		if ok {
+			catalog, err := response_writer.LoadMessageCatalogFile(messagesFile)
+			if err != nil {
+				PrintfW("could not load messages file \"%s\": %s", messagesFile, err)
+			} else {
+				defaultConfiguration.ResponseWriter = response_writer.NewRateLimiterDefaultResponseWriterWithCatalog(catalog)
+				config.ResponseWriter = defaultConfiguration.ResponseWriter
+				if config.ContentNegotiator == defaultConfiguration.ContentNegotiator {
+					config.ContentNegotiator, _ = response_writer.NewRateLimiterContentNegotiatorWithCatalog(nil, catalog)
+				}
+				PrintfWD(config, "using env %s", envKeyMessagesFile)
+			}
+		}
+	}
+
+	if config.RequestResponseWriter != nil {
+		PrintfWD(config, "using RequestResponseWriter Custom")
+		return
 	}
 
 	if config.ResponseWriter != defaultConfiguration.ResponseWriter {
@@ -246,3 +658,290 @@ func configureResponseWriter(config *LimiterConfig, defaultConfiguration *Limite
 		PrintfWD(config, "using ResponseWriter Default")
 	}
 }
+
+func configureHeadersMode(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		headersMode, ok := GetEnvString(envKeyHeadersMode)
+		if ok && config.useEnv(envKeyHeadersMode) {
+			config.HeadersMode = headersMode
+			PrintfWD(config, "using env %s", envKeyHeadersMode)
+		}
+	}
+
+	switch config.GetHeadersMode() {
+	case HeadersModeIETF, HeadersModeLegacy, HeadersModeNone:
+	default:
+		PrintfWD(config, "unknown headers mode \"%s\": using \"%s\"", config.HeadersMode, HeadersModeIETF)
+		config.HeadersMode = HeadersModeIETF
+	}
+}
+
+// getEnvWindowLimits panics when the env is set to limits it cannot parse, as configureLimits does for invalid limits,
+// naming it logKey.
+func getEnvWindowLimits(key string, logKey string) ([]*WindowLimit, bool) {
+	value, ok := GetEnvString(key)
+	if !ok {
+		return nil, false
+	}
+	limits, err := ParseWindowLimits(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid rate limit env %s: %s", logKey, err))
+	}
+	return limits, true
+}
+
+func configureTokenSources(config *LimiterConfig) {
+	if config.DisableEnvs {
+		return
+	}
+
+	value, ok := GetEnvString(envKeyTokenSources)
+	if !ok {
+		return
+	}
+	sources, err := ParseTokenSources(value)
+	if err != nil {
+		PrintfW("ignoring env %s: %s", envKeyTokenSources, err)
+		return
+	}
+	if config.useEnv(envKeyTokenSources) {
+		config.TokenSources = sources
+		PrintfWD(config, "using env %s", envKeyTokenSources)
+	}
+}
+
+func configureUnknownTokenPolicy(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		unknownTokenPolicy, ok := GetEnvString(envKeyUnknownTokenPolicy)
+		if ok && config.useEnv(envKeyUnknownTokenPolicy) {
+			config.UnknownTokenPolicy = unknownTokenPolicy
+			PrintfWD(config, "using env %s", envKeyUnknownTokenPolicy)
+		}
+	}
+
+	switch config.GetUnknownTokenPolicy() {
+	case UnknownTokenPolicyAllow, UnknownTokenPolicyIP, UnknownTokenPolicyReject:
+	default:
+		PrintfWD(config, "unknown token policy \"%s\": using \"%s\"", config.UnknownTokenPolicy, UnknownTokenPolicyAllow)
+		config.UnknownTokenPolicy = UnknownTokenPolicyAllow
+	}
+}
+
+func configureEvaluationPolicy(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		evaluationPolicy, ok := GetEnvString(envKeyEvaluationPolicy)
+		if ok && config.useEnv(envKeyEvaluationPolicy) {
+			config.EvaluationPolicy = evaluationPolicy
+			PrintfWD(config, "using env %s", envKeyEvaluationPolicy)
+		}
+	}
+
+	switch config.GetEvaluationPolicy() {
+	case EvaluationPolicyTokenThenIP, EvaluationPolicyToken, EvaluationPolicyIP, EvaluationPolicyBoth:
+	default:
+		PrintfWD(config, "unknown evaluation policy \"%s\": using \"%s\"", config.EvaluationPolicy, EvaluationPolicyTokenThenIP)
+		config.EvaluationPolicy = EvaluationPolicyTokenThenIP
+	}
+}
+
+// configureLimits panics on an invalid limit, which would otherwise fail every request it applies to.
+func configureLimits(config *LimiterConfig) {
+	rateConfigs := map[string]*RateConfig{"ip": config.IP, "token": config.Token}
+	if config.CustomTokens != nil {
+		for token, rateConfig := range *config.CustomTokens {
+			rateConfigs["tokens."+config.HashToken(token)] = rateConfig
+		}
+	}
+	if config.CustomIPs != nil {
+		for ip, rateConfig := range *config.CustomIPs {
+			rateConfigs["ips."+ip] = rateConfig
+		}
+	}
+
+	for valuePath, rateConfig := range rateConfigs {
+		if rateConfig == nil {
+			continue
+		}
+		if err := rateConfig.Validate(); err != nil {
+			panic(fmt.Sprintf("invalid rate limit \"%s\": %s", valuePath, err))
+		}
+		if err := config.checkStorageSupport(rateConfig); err != nil {
+			panic(fmt.Sprintf("invalid rate limit \"%s\": %s", valuePath, err))
+		}
+	}
+}
+
+// checkStorageSupport tells whether the storage adapter runs the algorithm of rateConfig, which would otherwise fail every
+// request it applies to.
+func (c *LimiterConfig) checkStorageSupport(rateConfig *RateConfig) error {
+	if rateConfig == nil || c.StorageAdapter == nil {
+		return nil
+	}
+	return adapters.CheckSupport(c.StorageAdapter, newCheckRequest(rateConfig, rateConfig.GetLimits()))
+}
+
+func configureRoutes(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		routesFile, ok := GetEnvString(envKeyRoutesFile)
+		if ok && config.useEnv(envKeyRoutesFile) {
+			routes, err := LoadRouteRulesFile(routesFile)
+			if err != nil {
+				PrintfW("could not load routes file \"%s\": %s", routesFile, err)
+			} else {
+				config.Routes = routes
+				PrintfWD(config, "using env %s", envKeyRoutesFile)
+			}
+		}
+	}
+
+	routes := []*RouteRule{}
+	names := map[string]string{}
+	for _, route := range config.Routes {
+		if route == nil {
+			continue
+		}
+		if err := route.Compile(); err != nil {
+			PrintfW("ignoring route rule \"%s\": %s", route.GetName(), err)
+			continue
+		}
+		if err := config.checkStorageSupport(route.Rate); err != nil {
+			PrintfW("ignoring route rule \"%s\": %s", route.GetName(), err)
+			continue
+		}
+		if name, ok := names[normalizeScope(route.GetScope())]; ok {
+			PrintfW("ignoring route rule \"%s\": its name collides with route rule \"%s\"", route.GetName(), name)
+			continue
+		}
+		names[normalizeScope(route.GetScope())] = route.GetName()
+		routes = append(routes, route)
+	}
+	config.Routes = routes
+}
+
+func configureTokenHashSecret(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		tokenHashSecret, ok := GetEnvString(envKeyTokenHashSecret)
+		if ok {
+			config.TokenHashSecret = tokenHashSecret
+			PrintfWD(config, "using env %s", envKeyTokenHashSecret)
+		}
+	}
+}
+
+// warnUnhashedTokens warns even without debug when tokens would reach a shared storage, like Redis, as they are.
+func warnUnhashedTokens(config *LimiterConfig) {
+	if config.TokenHashSecret != "" {
+		return
+	}
+	if _, ok := config.StorageAdapter.(*adapters.RateLimitMemoryStorageAdapter); ok {
+		return
+	}
+	PrintfW("token hash secret not set: tokens are stored without hashing, set %s", envKeyTokenHashSecret)
+}
+
+func configureClientIP(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		trustedProxies, ok := GetEnvList(envKeyTrustedProxies)
+		if ok && config.useEnv(envKeyTrustedProxies) {
+			config.TrustedProxies = trustedProxies
+			PrintfWD(config, "using env %s", envKeyTrustedProxies)
+		}
+
+		clientIPHeader, ok := GetEnvString(envKeyClientIPHeader)
+		if ok && config.useEnv(envKeyClientIPHeader) {
+			config.ClientIPHeader = clientIPHeader
+			PrintfWD(config, "using env %s", envKeyClientIPHeader)
+		}
+	}
+
+	trustedProxyPrefixes, err := ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		PrintfWD(config, "ignoring trusted proxies: %s", err)
+		config.TrustedProxies = nil
+		trustedProxyPrefixes = []netip.Prefix{}
+	}
+	config.trustedProxyPrefixes = trustedProxyPrefixes
+}
+
+func configureAccessLists(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		envLists := []struct {
+			key    string
+			target *[]string
+		}{
+			{envKeyAllowedIPs, &config.AllowedIPs},
+			{envKeyDeniedIPs, &config.DeniedIPs},
+			{envKeyAllowedTokens, &config.AllowedTokens},
+			{envKeyDeniedTokens, &config.DeniedTokens},
+		}
+		for _, envList := range envLists {
+			values, ok := GetEnvList(envList.key)
+			if ok && config.useEnv(envList.key) {
+				*envList.target = values
+				PrintfWD(config, "using env %s", envList.key)
+			}
+		}
+	}
+
+	config.accessLists = parseAccessLists(config)
+}
+
+func configureRules(config *LimiterConfig) {
+	if !config.DisableEnvs {
+		rulesFile, ok := GetEnvString(envKeyRulesFile)
+		if ok && config.useEnv(envKeyRulesFile) {
+			ruleSet, err := LoadRulesFile(rulesFile)
+			if err != nil {
+				PrintfW("could not load rules file \"%s\": %s", rulesFile, err)
+			} else {
+				config.Rules = ruleSet.Rules
+				if ruleSet.Mode != "" {
+					config.RuleMode = ruleSet.Mode
+				}
+				PrintfWD(config, "using env %s", envKeyRulesFile)
+			}
+		}
+
+		ruleMode, ok := GetEnvString(envKeyRuleMode)
+		if ok && config.useEnv(envKeyRuleMode) {
+			config.RuleMode = ruleMode
+			PrintfWD(config, "using env %s", envKeyRuleMode)
+		}
+	}
+
+	switch config.GetRuleMode() {
+	case RuleModeFirstMatch, RuleModeAllMatch:
+	default:
+		PrintfW("unknown rule mode \"%s\": using \"%s\"", config.RuleMode, RuleModeFirstMatch)
+		config.RuleMode = RuleModeFirstMatch
+	}
+
+	rules := []*Rule{}
+	names := map[string]string{}
+	for _, rule := range config.Rules {
+		if rule == nil {
+			continue
+		}
+		if err := rule.Compile(); err != nil {
+			PrintfW("ignoring rule \"%s\": %s", rule.Name, err)
+			continue
+		}
+		if err := config.checkStorageSupport(rule.Rate); err != nil {
+			PrintfW("ignoring rule \"%s\": %s", rule.Name, err)
+			continue
+		}
+		if name, ok := names[normalizeScope(rule.GetScope())]; ok {
+			PrintfW("ignoring rule \"%s\": its name collides with rule \"%s\"", rule.Name, name)
+			continue
+		}
+		names[normalizeScope(rule.GetScope())] = rule.Name
+		rules = append(rules, rule)
+	}
+	config.Rules = rules
+}
+
+// normalizeScope returns the scope as the storage keys see it: lowercased and with "-" turned into "_", so scopes
+// with the same normalized value share the counters.
+func normalizeScope(scope string) string {
+	return strings.ToLower(strings.ReplaceAll(scope, "-", "_"))
+}
@@ -0,0 +1,340 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ConfigSourceDefault = "default"
+const ConfigSourceCode = "code"
+const ConfigSourceEnv = "env"
+const ConfigSourceFile = "file"
+
+// envKeyPaths maps the envs to the json path of the value they set, telling which ones the config file takes over.
+var envKeyPaths = map[string]string{
+	envKeyDebug:                      "debug",
+	envKeyIPMaxRequestsPerSecond:     "ip.maxRequestsPerSecond",
+	envKeyIPBlockTimeMilliseconds:    "ip.blockTimeMilliseconds",
+	envKeyIPWindowMilliseconds:       "ip.windowMilliseconds",
+	envKeyIPLimits:                   "ip.limits",
+	envKeyIPAlgorithm:                "ip.algorithm",
+	envKeyIPBucketCapacity:           "ip.bucketCapacity",
+	envKeyIPRefillRatePerSecond:      "ip.refillRatePerSecond",
+	envKeyIPv4PrefixLength:           "ip.ipv4PrefixLength",
+	envKeyIPv6PrefixLength:           "ip.ipv6PrefixLength",
+	envKeyTokenMaxRequestsPerSecond:  "token.maxRequestsPerSecond",
+	envKeyTokenBlockTimeMilliseconds: "token.blockTimeMilliseconds",
+	envKeyTokenWindowMilliseconds:    "token.windowMilliseconds",
+	envKeyTokenLimits:                "token.limits",
+	envKeyTokenAlgorithm:             "token.algorithm",
+	envKeyTokenBucketCapacity:        "token.bucketCapacity",
+	envKeyTokenRefillRatePerSecond:   "token.refillRatePerSecond",
+	envKeyHeadersMode:                "headersMode",
+	envKeyTokenSources:               "tokenSources",
+	envKeyTrustedProxies:             "trustedProxies",
+	envKeyClientIPHeader:             "clientIPHeader",
+	envKeyAllowedIPs:                 "allowedIPs",
+	envKeyDeniedIPs:                  "deniedIPs",
+	envKeyAllowedTokens:              "allowedTokens",
+	envKeyDeniedTokens:               "deniedTokens",
+	envKeyUnknownTokenPolicy:         "unknownTokenPolicy",
+	envKeyEvaluationPolicy:           "evaluationPolicy",
+	envKeyRoutesFile:                 "routes",
+	envKeyRulesFile:                  "rules",
+	envKeyRuleMode:                   "ruleMode",
+}
+
+// LoadConfigFile reads a LimiterConfig from a YAML file, when the extension is .yaml or .yml, or from a JSON file, using
+// the json names of the fields. The values missing from the file keep their defaults, and the ones in the file take
+// precedence over the envs when the config is passed to SetConfiguration.
+func LoadConfigFile(path string) (*LimiterConfig, error) {
+	config := getDefaultConfiguration()
+	// SetConfiguration fills these in, telling the default ones from the custom ones.
+	config.StorageAdapter = nil
+	config.ResponseWriter = nil
+	config.ContentNegotiator = nil
+	config.sources = map[string]string{}
+
+	err := loadConfigFile(config, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func loadConfigFile(config *LimiterConfig, path string) error {
+	content, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values interface{}
+	err = json.Unmarshal(content, &values)
+	if err != nil {
+		return err
+	}
+
+	// A misspelled field would otherwise be ignored, silently keeping the default of a limit.
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(config)
+	if err != nil {
+		return err
+	}
+
+	for _, valuePath := range getValuePaths("", values, false) {
+		config.setSource(valuePath, ConfigSourceFile)
+	}
+
+	// The entries of tokens and ips replace the ones set before as a whole, and the fields they leave out are filled in
+	// from token and ip by SetConfiguration.
+	if fileValues, ok := values.(map[string]interface{}); ok {
+		for _, mapPath := range []string{"tokens", "ips"} {
+			entries, _ := fileValues[mapPath].(map[string]interface{})
+			for key, entry := range entries {
+				valuePath := joinValuePath(mapPath, key)
+				config.setSource(valuePath, ConfigSourceFile)
+				if entry == nil {
+					continue
+				}
+				entryContent, err := json.Marshal(entry)
+				if err != nil {
+					return err
+				}
+				if config.fileRateConfigs == nil {
+					config.fileRateConfigs = map[string]json.RawMessage{}
+				}
+				config.fileRateConfigs[valuePath] = entryContent
+			}
+		}
+	}
+
+	return nil
+}
+
+// getFileRateConfig returns the tokens or ips entry of the config file in valuePath, with the fields the file leaves out
+// taken from defaultRateConfig, the way the envs of custom tokens and IPs fall back to the ones of token and ip.
+func (c *LimiterConfig) getFileRateConfig(valuePath string, defaultRateConfig *RateConfig) (*RateConfig, bool) {
+	content, ok := c.fileRateConfigs[valuePath]
+	if !ok || c.GetConfigSource(valuePath) != ConfigSourceFile {
+		return nil, false
+	}
+
+	rateConfig := *defaultRateConfig
+	err := json.Unmarshal(content, &rateConfig)
+	if err != nil {
+		return nil, false
+	}
+
+	return &rateConfig, true
+}
+
+// readConfigFile returns the content of a JSON file or, when the extension is .yaml or .yml, of a YAML file as JSON,
+// so both use the json tags of the configuration.
+func readConfigFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var value interface{}
+		err = yaml.Unmarshal(content, &value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(stringifyKeys(value))
+	default:
+		return content, nil
+	}
+}
+
+// stringifyKeys turns the keys YAML reads as numbers or booleans, like a numeric token under tokens, into strings, since
+// JSON only has string keys.
+func stringifyKeys(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typedValue {
+			typedValue[key] = stringifyKeys(child)
+		}
+		return typedValue
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for key, child := range typedValue {
+			converted[fmt.Sprint(key)] = stringifyKeys(child)
+		}
+		return converted
+	case []interface{}:
+		for i, child := range typedValue {
+			typedValue[i] = stringifyKeys(child)
+		}
+		return typedValue
+	default:
+		return value
+	}
+}
+
+func configureConfigFile(config *LimiterConfig) {
+	if config.DisableEnvs {
+		return
+	}
+
+	configFile, ok := GetEnvString(envKeyConfigFile)
+	if !ok {
+		return
+	}
+
+	err := loadConfigFile(config, configFile)
+	if err != nil {
+		panic(fmt.Sprintf("could not load config file \"%s\" set by %s env: %s", configFile, envKeyConfigFile, err))
+	}
+	PrintfWD(config, "using env %s", envKeyConfigFile)
+}
+
+// configureCodeSources marks the values set in code, unless the config came from LoadConfigFile.
+func configureCodeSources(config *LimiterConfig) {
+	if config.sources != nil {
+		return
+	}
+	config.sources = map[string]string{}
+
+	content, err := json.Marshal(config)
+	if err != nil {
+		return
+	}
+
+	var values interface{}
+	err = json.Unmarshal(content, &values)
+	if err != nil {
+		return
+	}
+
+	for _, valuePath := range getValuePaths("", values, true) {
+		config.setSource(valuePath, ConfigSourceCode)
+	}
+}
+
+// getValuePaths returns the json paths of the values, like "ip.maxRequestsPerSecond". Lists are a single value.
+func getValuePaths(prefix string, value interface{}, skipEmpty bool) []string {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		paths := []string{}
+		for key, child := range typedValue {
+			paths = append(paths, getValuePaths(joinValuePath(prefix, key), child, skipEmpty)...)
+		}
+		if len(typedValue) == 0 && !skipEmpty && prefix != "" {
+			paths = append(paths, prefix)
+		}
+		return paths
+	case []interface{}:
+		if skipEmpty && len(typedValue) == 0 {
+			return []string{}
+		}
+	case string:
+		if skipEmpty && typedValue == "" {
+			return []string{}
+		}
+	case float64:
+		if skipEmpty && typedValue == 0 {
+			return []string{}
+		}
+	case bool:
+		if skipEmpty && !typedValue {
+			return []string{}
+		}
+	case nil:
+		if skipEmpty {
+			return []string{}
+		}
+	}
+	return []string{prefix}
+}
+
+func joinValuePath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// useEnv tells whether an env can set its value, since the config file takes precedence over the envs.
+func (c *LimiterConfig) useEnv(envKey string) bool {
+	valuePath, ok := envKeyPaths[envKey]
+	if !ok {
+		return true
+	}
+	return c.useEnvForPath(envKey, valuePath)
+}
+
+func (c *LimiterConfig) useEnvForPath(envKey string, valuePath string) bool {
+	if c.isFromFile(valuePath) {
+		PrintfWD(c, "ignoring env %s: set by the config file", envKey)
+		return false
+	}
+	c.setSource(valuePath, ConfigSourceEnv)
+	return true
+}
+
+func (c *LimiterConfig) isFromFile(valuePath string) bool {
+	if c.GetConfigSource(valuePath) == ConfigSourceFile {
+		return true
+	}
+	for sourcePath, source := range c.sources {
+		if source == ConfigSourceFile && strings.HasPrefix(sourcePath, valuePath+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *LimiterConfig) setSource(valuePath string, source string) {
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	for sourcePath := range c.sources {
+		if strings.HasPrefix(sourcePath, valuePath+".") {
+			delete(c.sources, sourcePath)
+		}
+	}
+	c.sources[valuePath] = source
+}
+
+// GetConfigSource tells where the value in a json path, like "ip.maxRequestsPerSecond" or "tokens.abc.limits", came from:
+// ConfigSourceFile, ConfigSourceEnv, ConfigSourceCode or ConfigSourceDefault.
+func (c *LimiterConfig) GetConfigSource(valuePath string) string {
+	for valuePath != "" {
+		if source, ok := c.sources[valuePath]; ok {
+			return source
+		}
+		index := strings.LastIndex(valuePath, ".")
+		if index < 0 {
+			break
+		}
+		valuePath = valuePath[:index]
+	}
+	return ConfigSourceDefault
+}
+
+// GetConfigSources returns the source of every value not coming from the defaults, with the custom tokens hashed.
+func (c *LimiterConfig) GetConfigSources() map[string]string {
+	sources := map[string]string{}
+	for valuePath, source := range c.sources {
+		if strings.HasPrefix(valuePath, "tokens.") {
+			token, field, found := strings.Cut(strings.TrimPrefix(valuePath, "tokens."), ".")
+			valuePath = "tokens." + c.HashToken(token)
+			if found {
+				valuePath = joinValuePath(valuePath, field)
+			}
+		}
+		sources[valuePath] = source
+	}
+	return sources
+}
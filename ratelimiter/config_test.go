@@ -1,7 +1,9 @@
 package ratelimiter
 
 import (
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/danielzinhors/rate-limiter/ratelimiter/mocks"
@@ -25,7 +27,35 @@ func (s *ConfigTestSuite) SetupTest() {
 	os.Unsetenv(envKeyIPBlockTimeMilliseconds)
 	os.Unsetenv(envKeyTokenMaxRequestsPerSecond)
 	os.Unsetenv(envKeyTokenBlockTimeMilliseconds)
+	os.Unsetenv(envKeyIPWindowMilliseconds)
+	os.Unsetenv(envKeyTokenWindowMilliseconds)
+	os.Unsetenv(envKeyIPLimits)
+	os.Unsetenv(envKeyTokenLimits)
+	os.Unsetenv(envKeyIPAlgorithm)
+	os.Unsetenv(envKeyIPBucketCapacity)
+	os.Unsetenv(envKeyIPRefillRatePerSecond)
+	os.Unsetenv(envKeyIPv4PrefixLength)
+	os.Unsetenv(envKeyIPv6PrefixLength)
+	os.Unsetenv(envKeyTokenAlgorithm)
+	os.Unsetenv(envKeyTokenBucketCapacity)
+	os.Unsetenv(envKeyTokenRefillRatePerSecond)
 	os.Unsetenv(envKeyDebug)
+	os.Unsetenv(envKeyHeadersMode)
+	os.Unsetenv(envKeyMessagesFile)
+	os.Unsetenv(envKeyTokenSources)
+	os.Unsetenv(envKeyTrustedProxies)
+	os.Unsetenv(envKeyClientIPHeader)
+	os.Unsetenv(envKeyAllowedIPs)
+	os.Unsetenv(envKeyDeniedIPs)
+	os.Unsetenv(envKeyAllowedTokens)
+	os.Unsetenv(envKeyDeniedTokens)
+	os.Unsetenv(envKeyUnknownTokenPolicy)
+	os.Unsetenv(envKeyTokenHashSecret)
+	os.Unsetenv(envKeyEvaluationPolicy)
+	os.Unsetenv(envKeyRoutesFile)
+	os.Unsetenv(envKeyRulesFile)
+	os.Unsetenv(envKeyRuleMode)
+	os.Unsetenv(envKeyConfigFile)
 	os.Unsetenv(envUseRedis)
 	os.Unsetenv(envRedisAddress)
 	os.Unsetenv(envRedisPassword)
@@ -34,6 +64,15 @@ func (s *ConfigTestSuite) SetupTest() {
 	os.Unsetenv("RATE_LIMITER_TOKEN_abc_BLOCK_TIME")
 	os.Unsetenv("RATE_LIMITER_TOKEN_def_MAX_REQUESTS")
 	os.Unsetenv("RATE_LIMITER_TOKEN_def_BLOCK_TIME")
+	os.Unsetenv("RATE_LIMITER_TOKEN_ghi_MAX_REQUESTS")
+	os.Unsetenv("RATE_LIMITER_TOKEN_abc_WINDOW_TIME")
+	os.Unsetenv("RATE_LIMITER_TOKEN_abc_LIMITS")
+	os.Unsetenv("RATE_LIMITER_TOKEN_abc_ALGORITHM")
+	os.Unsetenv("RATE_LIMITER_TOKEN_abc_BUCKET_CAPACITY")
+	os.Unsetenv("RATE_LIMITER_TOKEN_abc_REFILL_RATE")
+	os.Unsetenv("RATE_LIMITER_IP_office_CIDR")
+	os.Unsetenv("RATE_LIMITER_IP_office_MAX_REQUESTS")
+	os.Unsetenv("RATE_LIMITER_IP_partner_MAX_REQUESTS")
 }
 
 func (s *ConfigTestSuite) TestGetDefaultConfiguration() {
@@ -43,6 +82,8 @@ func (s *ConfigTestSuite) TestGetDefaultConfiguration() {
 	assert.NotNil(s.T(), config.Token)
 	assert.NotNil(s.T(), config.CustomTokens)
 	assert.Empty(s.T(), config.CustomTokens)
+	assert.NotNil(s.T(), config.CustomIPs)
+	assert.Empty(s.T(), config.CustomIPs)
 	assert.NotNil(s.T(), config.StorageAdapter)
 	assert.NotNil(s.T(), config.ResponseWriter)
 	assert.Equal(s.T(), false, config.Debug)
@@ -206,6 +247,505 @@ func (s *ConfigTestSuite) TestSetConfiguration_ValuesFromEnv_CustomTokenDefaults
 	assert.Equal(s.T(), (*config.CustomTokens)["def"].BlockTimeMilliseconds, int64(888))
 }
 
+func (s *ConfigTestSuite) TestSetConfiguration_WindowFromEnv() {
+	os.Setenv(envKeyIPWindowMilliseconds, "60000")
+	os.Setenv(envKeyTokenWindowMilliseconds, "86400000")
+	os.Setenv("RATE_LIMITER_TOKEN_abc_WINDOW_TIME", "3600000")
+	os.Setenv("RATE_LIMITER_TOKEN_def_MAX_REQUESTS", "50000")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), int64(60000), config.IP.GetWindowMilliseconds())
+	assert.Equal(s.T(), int64(86400000), config.Token.GetWindowMilliseconds())
+	assert.Equal(s.T(), int64(3600000), (*config.CustomTokens)["abc"].GetWindowMilliseconds())
+	assert.Equal(s.T(), int64(86400000), (*config.CustomTokens)["def"].GetWindowMilliseconds())
+	assert.Equal(s.T(), int64(50000), (*config.CustomTokens)["def"].MaxRequestsPerSecond)
+}
+
+func (s *ConfigTestSuite) TestRateConfig_DefaultWindow() {
+	rateConfig := &RateConfig{MaxRequestsPerSecond: 10}
+	assert.Equal(s.T(), int64(1000), rateConfig.GetWindowMilliseconds())
+	assert.Equal(s.T(), []*WindowLimit{{MaxRequests: 10, WindowMilliseconds: 1000}}, rateConfig.GetLimits())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_LimitsFromEnv() {
+	os.Setenv(envKeyIPLimits, "20/1000,500/60000")
+	os.Setenv(envKeyTokenLimits, "100000/86400000")
+	os.Setenv("RATE_LIMITER_TOKEN_abc_LIMITS", "20/1000,500/60000,100000/86400000")
+	os.Setenv("RATE_LIMITER_TOKEN_def_MAX_REQUESTS", "10")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Len(s.T(), config.IP.Limits, 2)
+	assert.Equal(s.T(), int64(500), config.IP.Limits[1].MaxRequests)
+	assert.Len(s.T(), config.Token.Limits, 1)
+	assert.Len(s.T(), (*config.CustomTokens)["abc"].Limits, 3)
+	assert.Equal(s.T(), int64(86400000), (*config.CustomTokens)["abc"].Limits[2].WindowMilliseconds)
+	assert.Equal(s.T(), config.Token.Limits, (*config.CustomTokens)["def"].Limits)
+}
+
+func (s *ConfigTestSuite) TestRateConfig_Validate() {
+	assert.Nil(s.T(), (&RateConfig{MaxRequestsPerSecond: 10}).Validate())
+	assert.Nil(s.T(), (&RateConfig{Limits: []*WindowLimit{{MaxRequests: 0, WindowMilliseconds: 1000}}}).Validate())
+	assert.NotNil(s.T(), (&RateConfig{Limits: []*WindowLimit{{MaxRequests: 5}}}).Validate())
+	assert.NotNil(s.T(), (&RateConfig{Limits: []*WindowLimit{{MaxRequests: 5, WindowMilliseconds: -1}}}).Validate())
+	assert.NotNil(s.T(), (&RateConfig{Limits: []*WindowLimit{nil}}).Validate())
+	assert.NotNil(s.T(), (&RateConfig{Algorithm: AlgorithmGCRA}).Validate())
+	assert.NotNil(s.T(), (*RateConfig)(nil).Validate())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_InvalidLimits() {
+	assert.Panics(s.T(), func() {
+		SetConfiguration(&LimiterConfig{Token: &RateConfig{Limits: []*WindowLimit{{MaxRequests: 5}}}, DisableEnvs: true})
+	}, "should panic")
+	assert.Panics(s.T(), func() {
+		SetConfiguration(&LimiterConfig{
+			CustomTokens: &map[string]*RateConfig{"abc": {Limits: []*WindowLimit{{MaxRequests: 5}}}},
+			DisableEnvs:  true,
+		})
+	}, "should panic")
+
+	config := SetConfiguration(&LimiterConfig{
+		Routes: []*RouteRule{
+			{Path: "/login", Rate: &RateConfig{Limits: []*WindowLimit{{MaxRequests: 5}}}},
+			{Path: "/users"},
+		},
+		Rules:       []*Rule{{Name: "zero", Rate: &RateConfig{Limits: []*WindowLimit{{MaxRequests: 5}}}}},
+		DisableEnvs: true,
+	})
+	assert.Len(s.T(), config.Routes, 1)
+	assert.Equal(s.T(), "/users", config.Routes[0].Path)
+	assert.Len(s.T(), config.Rules, 0)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_InvalidLimitsFromEnv() {
+	os.Setenv(envKeyIPLimits, "20/1000,500/0")
+	assert.PanicsWithValue(s.T(), "invalid rate limit env LIMITS_RATE_LIMITER_IP: invalid window limit \"500/0\": window must be positive", func() {
+		SetConfiguration(nil)
+	})
+	os.Unsetenv(envKeyIPLimits)
+
+	os.Setenv("RATE_LIMITER_TOKEN_s3cr3ttoken_LIMITS", "20")
+	defer os.Unsetenv("RATE_LIMITER_TOKEN_s3cr3ttoken_LIMITS")
+	assert.PanicsWithValue(s.T(), "invalid rate limit env RATE_LIMITER_TOKEN_"+(&LimiterConfig{}).HashToken("s3cr3ttoken")+"_LIMITS: invalid window limit \"20\": expected <max requests>/<window milliseconds>", func() {
+		SetConfiguration(nil)
+	})
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_CustomIPsFromEnv() {
+	os.Setenv(envKeyIPv4PrefixLength, "24")
+	os.Setenv("RATE_LIMITER_IP_office_CIDR", "203.0.113.0/24")
+	os.Setenv("RATE_LIMITER_IP_office_MAX_REQUESTS", "1000")
+	os.Setenv("RATE_LIMITER_IP_partner_MAX_REQUESTS", "2000")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Len(s.T(), *config.CustomIPs, 1)
+	assert.Contains(s.T(), *config.CustomIPs, "203.0.113.0/24")
+	assert.Equal(s.T(), int64(1000), (*config.CustomIPs)["203.0.113.0/24"].MaxRequestsPerSecond)
+	assert.Equal(s.T(), config.IP.BlockTimeMilliseconds, (*config.CustomIPs)["203.0.113.0/24"].BlockTimeMilliseconds)
+	assert.Equal(s.T(), 24, (*config.CustomIPs)["203.0.113.0/24"].IPv4PrefixLength)
+
+	rateConfig, ok := config.GetRateLimiterRateConfigForIP("203.0.113.7")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), (*config.CustomIPs)["203.0.113.0/24"], rateConfig)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_CustomIPs() {
+	config := SetConfiguration(&LimiterConfig{
+		IP:    &RateConfig{MaxRequestsPerSecond: 10},
+		Token: &RateConfig{MaxRequestsPerSecond: 20},
+		CustomIPs: &map[string]*RateConfig{
+			"198.51.100.0/24": {MaxRequestsPerSecond: 100},
+			"198.51.100.10":   nil,
+			"invalid":         {MaxRequestsPerSecond: 300},
+		},
+		DisableEnvs: true,
+	})
+
+	assert.Equal(s.T(), config.IP, (*config.CustomIPs)["198.51.100.10"])
+	assert.Len(s.T(), config.customIPPrefixes, 2)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_AccessListsFromEnv() {
+	os.Setenv(envKeyAllowedIPs, "10.0.0.0/8, 192.0.2.1")
+	os.Setenv(envKeyDeniedIPs, "10.6.6.6")
+	os.Setenv(envKeyAllowedTokens, "health")
+	os.Setenv(envKeyDeniedTokens, "revoked,stolen")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), []string{"10.0.0.0/8", "192.0.2.1"}, config.AllowedIPs)
+	assert.Equal(s.T(), []string{"revoked", "stolen"}, config.DeniedTokens)
+	assert.Equal(s.T(), AccessAllowed, config.GetAccess("192.0.2.1", ""))
+	assert.Equal(s.T(), AccessDenied, config.GetAccess("10.6.6.6", ""))
+	assert.Equal(s.T(), AccessDenied, config.GetAccess("192.0.2.9", "stolen"))
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_UnknownTokenPolicyFromEnv() {
+	os.Setenv(envKeyUnknownTokenPolicy, UnknownTokenPolicyReject)
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), UnknownTokenPolicyReject, config.GetUnknownTokenPolicy())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_InvalidUnknownTokenPolicy() {
+	os.Setenv(envKeyUnknownTokenPolicy, "strict")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), UnknownTokenPolicyAllow, config.GetUnknownTokenPolicy())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_TokenHashSecretFromEnv() {
+	os.Setenv(envKeyTokenHashSecret, "secret")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), "secret", config.TokenHashSecret)
+	assert.Equal(s.T(), config.HashToken("abc"), config.GetStorageKey("TOKEN", "abc"))
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_EvaluationPolicyFromEnv() {
+	os.Setenv(envKeyEvaluationPolicy, EvaluationPolicyBoth)
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), EvaluationPolicyBoth, config.GetEvaluationPolicy())
+
+	os.Setenv(envKeyEvaluationPolicy, "all")
+
+	config = SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), EvaluationPolicyTokenThenIP, config.GetEvaluationPolicy())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_RoutesFile() {
+	path := filepath.Join(s.T().TempDir(), "routes.json")
+	os.WriteFile(path, []byte(`[{"path": "/login", "methods": ["POST"], "rate": {"maxRequestsPerSecond": 5}}, {"path": "/users/{id:[0-9}"}]`), 0644)
+	os.Setenv(envKeyRoutesFile, path)
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Len(s.T(), config.Routes, 1)
+	assert.Equal(s.T(), "POST /login", config.Routes[0].GetName())
+	assert.Equal(s.T(), config.Routes[0], config.GetRouteRule("POST", "/login"))
+
+	os.Setenv(envKeyRoutesFile, filepath.Join(s.T().TempDir(), "missing.json"))
+	config = SetConfiguration(&LimiterConfig{Routes: []*RouteRule{{Path: "/login"}}})
+	assert.Len(s.T(), config.Routes, 1)
+	assert.Equal(s.T(), "/login", config.Routes[0].Path)
+}
+
+func (s *ConfigTestSuite) TestLoadConfigFile() {
+	path := filepath.Join(s.T().TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+ip:
+  maxRequestsPerSecond: 5
+tokens:
+  abc:
+    maxRequestsPerSecond: 30
+    blockTimeMilliseconds: 100
+headersMode: legacy
+routes:
+  - path: /login
+    methods: [POST]
+`), 0644)
+
+	config, err := LoadConfigFile(path)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), int64(5), config.IP.MaxRequestsPerSecond)
+	assert.Equal(s.T(), int64(1000), config.IP.BlockTimeMilliseconds)
+	assert.Equal(s.T(), int64(200), config.Token.MaxRequestsPerSecond)
+	assert.Equal(s.T(), int64(30), (*config.CustomTokens)["abc"].MaxRequestsPerSecond)
+	assert.Equal(s.T(), HeadersModeLegacy, config.HeadersMode)
+	assert.Len(s.T(), config.Routes, 1)
+	assert.Equal(s.T(), ConfigSourceFile, config.GetConfigSource("ip.maxRequestsPerSecond"))
+	assert.Equal(s.T(), ConfigSourceDefault, config.GetConfigSource("ip.blockTimeMilliseconds"))
+	assert.Equal(s.T(), ConfigSourceFile, config.GetConfigSource("tokens.abc.maxRequestsPerSecond"))
+	assert.Equal(s.T(), ConfigSourceFile, config.GetConfigSource("tokens.abc.algorithm"))
+
+	config = SetConfiguration(config)
+	assert.NotNil(s.T(), config.StorageAdapter)
+	assert.NotNil(s.T(), config.ResponseWriter)
+	assert.NotNil(s.T(), config.GetRouteRule("POST", "/login"))
+	assert.Equal(s.T(), ConfigSourceDefault, config.GetConfigSource("token.maxRequestsPerSecond"))
+
+	_, err = LoadConfigFile(filepath.Join(s.T().TempDir(), "missing.json"))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_ConfigFilePrecedence() {
+	path := filepath.Join(s.T().TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"ip": {"maxRequestsPerSecond": 5}, "tokens": {"abc": {"maxRequestsPerSecond": 30}}, "evaluationPolicy": "both"}`), 0644)
+	os.Setenv(envKeyConfigFile, path)
+	os.Setenv(envKeyIPMaxRequestsPerSecond, "50")
+	os.Setenv(envKeyIPBlockTimeMilliseconds, "2000")
+	os.Setenv(envKeyEvaluationPolicy, EvaluationPolicyIP)
+	os.Setenv("RATE_LIMITER_TOKEN_abc_MAX_REQUESTS", "70")
+	os.Setenv("RATE_LIMITER_TOKEN_ghi_MAX_REQUESTS", "80")
+
+	config := SetConfiguration(&LimiterConfig{HeadersMode: HeadersModeLegacy})
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), int64(5), config.IP.MaxRequestsPerSecond)
+	assert.Equal(s.T(), int64(2000), config.IP.BlockTimeMilliseconds)
+	assert.Equal(s.T(), EvaluationPolicyBoth, config.EvaluationPolicy)
+	assert.Equal(s.T(), int64(30), (*config.CustomTokens)["abc"].MaxRequestsPerSecond)
+	assert.Equal(s.T(), int64(80), (*config.CustomTokens)["ghi"].MaxRequestsPerSecond)
+	assert.Equal(s.T(), HeadersModeLegacy, config.HeadersMode)
+
+	assert.Equal(s.T(), ConfigSourceFile, config.GetConfigSource("ip.maxRequestsPerSecond"))
+	assert.Equal(s.T(), ConfigSourceEnv, config.GetConfigSource("ip.blockTimeMilliseconds"))
+	assert.Equal(s.T(), ConfigSourceFile, config.GetConfigSource("evaluationPolicy"))
+	assert.Equal(s.T(), ConfigSourceFile, config.GetConfigSource("tokens.abc.maxRequestsPerSecond"))
+	assert.Equal(s.T(), ConfigSourceEnv, config.GetConfigSource("tokens.ghi.maxRequestsPerSecond"))
+	assert.Equal(s.T(), ConfigSourceCode, config.GetConfigSource("headersMode"))
+	assert.Equal(s.T(), ConfigSourceDefault, config.GetConfigSource("token.maxRequestsPerSecond"))
+
+	sources := config.GetConfigSources()
+	assert.Equal(s.T(), ConfigSourceEnv, sources["tokens."+config.HashToken("ghi")])
+	assert.NotContains(s.T(), sources, "tokens.ghi")
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_ConfigFileInvalid() {
+	path := filepath.Join(s.T().TempDir(), "config.yml")
+	os.WriteFile(path, []byte("ip: [\n"), 0644)
+	os.Setenv(envKeyConfigFile, path)
+
+	assert.Panics(s.T(), func() { SetConfiguration(nil) }, "should panic")
+
+	os.Setenv(envKeyConfigFile, filepath.Join(s.T().TempDir(), "missing.yml"))
+	assert.Panics(s.T(), func() { SetConfiguration(nil) }, "should panic")
+}
+
+func (s *ConfigTestSuite) TestLoadConfigFile_UnknownField() {
+	path := filepath.Join(s.T().TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+token:
+  maxRequestPerSecond: 5
+`), 0644)
+
+	_, err := LoadConfigFile(path)
+	assert.NotNil(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "maxRequestPerSecond")
+
+	os.Setenv(envKeyConfigFile, path)
+	assert.Panics(s.T(), func() { SetConfiguration(nil) }, "should panic")
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_ConfigFilePartialEntries() {
+	path := filepath.Join(s.T().TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+ip:
+  maxRequestsPerSecond: 5
+  blockTimeMilliseconds: 3000
+  ipv4PrefixLength: 24
+token:
+  blockTimeMilliseconds: 2000
+  windowMilliseconds: 60000
+tokens:
+  abc:
+    maxRequestsPerSecond: 30
+ips:
+  10.0.0.0/8:
+    blockTimeMilliseconds: 0
+`), 0644)
+	os.Setenv(envKeyConfigFile, path)
+	os.Setenv(envKeyTokenMaxRequestsPerSecond, "70")
+
+	config := SetConfiguration(nil)
+	abc := (*config.CustomTokens)["abc"]
+	assert.Equal(s.T(), int64(30), abc.MaxRequestsPerSecond)
+	assert.Equal(s.T(), int64(2000), abc.BlockTimeMilliseconds)
+	assert.Equal(s.T(), int64(60000), abc.WindowMilliseconds)
+	assert.Equal(s.T(), int64(70), config.Token.MaxRequestsPerSecond)
+
+	network := (*config.CustomIPs)["10.0.0.0/8"]
+	assert.Equal(s.T(), int64(5), network.MaxRequestsPerSecond)
+	assert.Equal(s.T(), int64(0), network.BlockTimeMilliseconds)
+	assert.Equal(s.T(), 24, network.IPv4PrefixLength)
+}
+
+func (s *ConfigTestSuite) TestLoadConfigFile_NumericTokens() {
+	path := filepath.Join(s.T().TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+tokens:
+  123456:
+    maxRequestsPerSecond: 5
+  true:
+    maxRequestsPerSecond: 10
+`), 0644)
+
+	config, err := LoadConfigFile(path)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), int64(5), (*config.CustomTokens)["123456"].MaxRequestsPerSecond)
+	assert.Equal(s.T(), int64(10), (*config.CustomTokens)["true"].MaxRequestsPerSecond)
+	assert.Equal(s.T(), ConfigSourceFile, config.GetConfigSource("tokens.123456.maxRequestsPerSecond"))
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_RulesFile() {
+	path := filepath.Join(s.T().TempDir(), "rules.yaml")
+	os.WriteFile(path, []byte(`
+mode: all_match
+rules:
+  - name: mobile
+    match:
+      path: /v2/*
+      userAgent: "*Android*"
+    rate:
+      maxRequestsPerSecond: 50
+  - name: invalid
+`), 0644)
+	os.Setenv(envKeyRulesFile, path)
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), RuleModeAllMatch, config.GetRuleMode())
+	assert.Len(s.T(), config.Rules, 1)
+	assert.Equal(s.T(), "mobile", config.Rules[0].Name)
+
+	os.Setenv(envKeyRuleMode, "any")
+	config = SetConfiguration(nil)
+	assert.Equal(s.T(), RuleModeFirstMatch, config.GetRuleMode())
+
+	os.Setenv(envKeyRuleMode, RuleModeFirstMatch)
+	config = SetConfiguration(nil)
+	assert.Equal(s.T(), RuleModeFirstMatch, config.GetRuleMode())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_DuplicateRuleNames() {
+	config := SetConfiguration(&LimiterConfig{Rules: []*Rule{
+		{Name: "login", Rate: &RateConfig{MaxRequestsPerSecond: 5}},
+		{Name: "Login", Rate: &RateConfig{MaxRequestsPerSecond: 50}},
+		{Name: "login", Rate: &RateConfig{MaxRequestsPerSecond: 500}},
+		{Name: "signup", Rate: &RateConfig{MaxRequestsPerSecond: 5}},
+	}})
+	assert.Len(s.T(), config.Rules, 2)
+	assert.Equal(s.T(), "login", config.Rules[0].Name)
+	assert.Equal(s.T(), int64(5), config.Rules[0].Rate.MaxRequestsPerSecond)
+	assert.Equal(s.T(), "signup", config.Rules[1].Name)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_DuplicateRouteNames() {
+	config := SetConfiguration(&LimiterConfig{Routes: []*RouteRule{
+		{Name: "burst-limit", Path: "/login", Rate: &RateConfig{MaxRequestsPerSecond: 5}},
+		{Name: "Burst_Limit", Path: "/signup", Rate: &RateConfig{MaxRequestsPerSecond: 50}},
+		{Path: "/Users/*"},
+		{Path: "/users/*"},
+		{Path: "/users/*", Methods: []string{"POST"}},
+	}})
+	assert.Len(s.T(), config.Routes, 3)
+	assert.Equal(s.T(), "burst-limit", config.Routes[0].GetName())
+	assert.Equal(s.T(), "/Users/*", config.Routes[1].GetName())
+	assert.Equal(s.T(), "POST /users/*", config.Routes[2].GetName())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_DuplicateNormalizedRuleNames() {
+	config := SetConfiguration(&LimiterConfig{Rules: []*Rule{
+		{Name: "burst-limit", Rate: &RateConfig{MaxRequestsPerSecond: 5}},
+		{Name: "burst_limit", Rate: &RateConfig{MaxRequestsPerSecond: 50}},
+		{Name: "Burst_Limit", Rate: &RateConfig{MaxRequestsPerSecond: 500}},
+	}})
+	assert.Len(s.T(), config.Rules, 1)
+	assert.Equal(s.T(), "burst-limit", config.Rules[0].Name)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_IPPrefixLengthFromEnv() {
+	os.Setenv(envKeyIPv4PrefixLength, "24")
+	os.Setenv(envKeyIPv6PrefixLength, "64")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), 24, config.IP.IPv4PrefixLength)
+	assert.Equal(s.T(), 64, config.IP.IPv6PrefixLength)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_TokenBucketFromEnv() {
+	os.Setenv(envKeyIPAlgorithm, AlgorithmTokenBucket)
+	os.Setenv(envKeyIPBucketCapacity, "30")
+	os.Setenv(envKeyIPRefillRatePerSecond, "3")
+	os.Setenv(envKeyTokenAlgorithm, AlgorithmTokenBucket)
+	os.Setenv(envKeyTokenBucketCapacity, "40")
+	os.Setenv(envKeyTokenRefillRatePerSecond, "4")
+	os.Setenv("RATE_LIMITER_TOKEN_abc_BUCKET_CAPACITY", "50")
+
+	config := SetConfiguration(nil)
+	assert.NotNil(s.T(), config)
+	assert.Equal(s.T(), AlgorithmTokenBucket, config.IP.Algorithm)
+	assert.Equal(s.T(), int64(30), config.IP.BucketCapacity)
+	assert.Equal(s.T(), int64(3), config.IP.RefillRatePerSecond)
+	assert.Equal(s.T(), AlgorithmTokenBucket, config.Token.Algorithm)
+	assert.Equal(s.T(), int64(40), config.Token.BucketCapacity)
+	assert.Equal(s.T(), int64(4), config.Token.RefillRatePerSecond)
+	assert.Contains(s.T(), *config.CustomTokens, "abc")
+	assert.Equal(s.T(), AlgorithmTokenBucket, (*config.CustomTokens)["abc"].Algorithm)
+	assert.Equal(s.T(), int64(50), (*config.CustomTokens)["abc"].BucketCapacity)
+	assert.Equal(s.T(), int64(4), (*config.CustomTokens)["abc"].RefillRatePerSecond)
+}
+
+func (s *ConfigTestSuite) TestRateConfig_TokenBucketDefaults() {
+	rateConfig := &RateConfig{MaxRequestsPerSecond: 10}
+	assert.Equal(s.T(), AlgorithmSlidingLog, rateConfig.GetAlgorithm())
+	assert.Equal(s.T(), int64(10), rateConfig.GetBucketCapacity())
+	assert.Equal(s.T(), int64(10), rateConfig.GetRefillRatePerSecond())
+
+	rateConfig = &RateConfig{MaxRequestsPerSecond: 10, Algorithm: AlgorithmTokenBucket, BucketCapacity: 20, RefillRatePerSecond: 5}
+	assert.Equal(s.T(), AlgorithmTokenBucket, rateConfig.GetAlgorithm())
+	assert.Equal(s.T(), int64(20), rateConfig.GetBucketCapacity())
+	assert.Equal(s.T(), int64(5), rateConfig.GetRefillRatePerSecond())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_AlgorithmNotSupported() {
+	storageAdapterMock := mocks.NewMockRateLimitStorageAdapter(s.controller)
+
+	assert.Panics(s.T(), func() {
+		SetConfiguration(&LimiterConfig{
+			Token:          &RateConfig{MaxRequestsPerSecond: 10, Algorithm: AlgorithmTokenBucket},
+			StorageAdapter: storageAdapterMock,
+			DisableEnvs:    true,
+		})
+	}, "should panic")
+	assert.Panics(s.T(), func() {
+		SetConfiguration(&LimiterConfig{
+			IP:             &RateConfig{MaxRequestsPerSecond: 10, WindowMilliseconds: 60000},
+			StorageAdapter: storageAdapterMock,
+			DisableEnvs:    true,
+		})
+	}, "should panic")
+
+	config := SetConfiguration(&LimiterConfig{
+		IP:             &RateConfig{MaxRequestsPerSecond: 10},
+		Token:          &RateConfig{MaxRequestsPerSecond: 10},
+		StorageAdapter: storageAdapterMock,
+		Routes: []*RouteRule{
+			{Path: "/login", Rate: &RateConfig{MaxRequestsPerSecond: 5, Algorithm: AlgorithmGCRA}},
+			{Path: "/users", Rate: &RateConfig{MaxRequestsPerSecond: 5}},
+		},
+		Rules:       []*Rule{{Name: "fixed", Rate: &RateConfig{MaxRequestsPerSecond: 5, Algorithm: AlgorithmFixedWindow}}},
+		DisableEnvs: true,
+	})
+	assert.Len(s.T(), config.Routes, 1)
+	assert.Equal(s.T(), "/users", config.Routes[0].Path)
+	assert.Len(s.T(), config.Rules, 0)
+}
+
+func (s *ConfigTestSuite) TestRateConfig_BucketDefaultsFromLimits() {
+	rateConfig := &RateConfig{Algorithm: AlgorithmTokenBucket, Limits: []*WindowLimit{{MaxRequests: 300, WindowMilliseconds: 60000}}}
+	assert.Equal(s.T(), int64(300), rateConfig.GetBucketCapacity())
+	assert.Equal(s.T(), int64(5), rateConfig.GetRefillRatePerSecond())
+	assert.Nil(s.T(), rateConfig.Validate())
+
+	rateConfig = &RateConfig{Algorithm: AlgorithmTokenBucket, Limits: []*WindowLimit{{MaxRequests: 5, WindowMilliseconds: 60000}}}
+	assert.Equal(s.T(), int64(0), rateConfig.GetRefillRatePerSecond())
+	assert.NotNil(s.T(), rateConfig.Validate())
+
+	rateConfig.RefillRatePerSecond = 1
+	assert.Nil(s.T(), rateConfig.Validate())
+
+	assert.NotNil(s.T(), (&RateConfig{Algorithm: AlgorithmTokenBucket}).Validate())
+}
+
 func (s *ConfigTestSuite) TestSetConfiguration_RedisAdapter() {
 	os.Setenv(envUseRedis, "true")
 	os.Setenv(envRedisAddress, "localhost:6379")
@@ -251,3 +791,96 @@ func (s *ConfigTestSuite) TestGetRateConfigForToken() {
 	assert.Equal(s.T(), int64(444), zzzConfig.BlockTimeMilliseconds)
 	assert.Equal(s.T(), false, zzzIsCustom)
 }
+
+func (s *ConfigTestSuite) TestSetConfiguration_HeadersMode() {
+	config := SetConfiguration(&LimiterConfig{DisableEnvs: true})
+	assert.Equal(s.T(), HeadersModeIETF, config.GetHeadersMode())
+
+	os.Setenv(envKeyHeadersMode, HeadersModeLegacy)
+	config = SetConfiguration(nil)
+	assert.Equal(s.T(), HeadersModeLegacy, config.GetHeadersMode())
+
+	os.Setenv(envKeyHeadersMode, "unknown")
+	config = SetConfiguration(nil)
+	assert.Equal(s.T(), HeadersModeIETF, config.GetHeadersMode())
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_ContentNegotiator() {
+	config := SetConfiguration(&LimiterConfig{DisableEnvs: true})
+	assert.NotNil(s.T(), config.ContentNegotiator)
+
+	responseWriterMock := mocks.NewMockRateLimiterResponseWriter(s.controller)
+	config = SetConfiguration(&LimiterConfig{DisableEnvs: true, ResponseWriter: responseWriterMock})
+	assert.Nil(s.T(), config.ContentNegotiator)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_MessagesFile() {
+	path := filepath.Join(s.T().TempDir(), "messages.json")
+	os.WriteFile(path, []byte(`{"fr": "trop de requêtes"}`), 0644)
+	os.Setenv(envKeyMessagesFile, path)
+
+	config := SetConfiguration(nil)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("Accept-Language", "fr")
+	config.GetRequestResponseWriter(request).WriteBlockedResponse(recorder, request, &RateLimitDecision{})
+
+	assert.Equal(s.T(), "trop de requêtes", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	request.Header.Set("Accept", "application/json")
+	config.GetRequestResponseWriter(request).WriteBlockedResponse(recorder, request, &RateLimitDecision{})
+
+	assert.Contains(s.T(), recorder.Body.String(), `"message":"trop de requêtes"`)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_MessagesFileInvalid() {
+	os.Setenv(envKeyMessagesFile, filepath.Join(s.T().TempDir(), "missing.json"))
+
+	output, _ := captureOutput(func() error {
+		config := SetConfiguration(nil)
+		assert.NotNil(s.T(), config.ResponseWriter)
+		return nil
+	})
+	assert.Contains(s.T(), output, "could not load messages file")
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_TokenSources() {
+	config := SetConfiguration(&LimiterConfig{DisableEnvs: true})
+	assert.Equal(s.T(), []*TokenSource{{Type: TokenSourceHeader, Name: "API_KEY"}}, config.GetTokenSources())
+
+	os.Setenv(envKeyTokenSources, "header:X-API-Key, bearer, query:api_key, cookie:api_key")
+	config = SetConfiguration(nil)
+	assert.Equal(s.T(), []*TokenSource{
+		{Type: TokenSourceHeader, Name: "X-API-Key"},
+		{Type: TokenSourceBearer},
+		{Type: TokenSourceQuery, Name: "api_key"},
+		{Type: TokenSourceCookie, Name: "api_key"},
+	}, config.GetTokenSources())
+
+	os.Setenv(envKeyTokenSources, "form:api_key")
+	config = SetConfiguration(nil)
+	assert.Nil(s.T(), config.TokenSources)
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_TrustedProxies() {
+	os.Setenv(envKeyTrustedProxies, "10.0.0.0/8, 192.168.0.1")
+	os.Setenv(envKeyClientIPHeader, "X-Real-IP")
+
+	config := SetConfiguration(nil)
+	assert.Equal(s.T(), []string{"10.0.0.0/8", "192.168.0.1"}, config.TrustedProxies)
+	assert.Equal(s.T(), "X-Real-IP", config.GetClientIPHeader())
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "10.1.2.3:1234"
+	request.Header.Set("X-Real-IP", "203.0.113.7")
+	assert.Equal(s.T(), "203.0.113.7", config.GetClientIP(request))
+}
+
+func (s *ConfigTestSuite) TestSetConfiguration_TrustedProxiesInvalid() {
+	os.Setenv(envKeyTrustedProxies, "10.0.0.0/8,not-an-ip")
+
+	config := SetConfiguration(nil)
+	assert.Nil(s.T(), config.TrustedProxies)
+}
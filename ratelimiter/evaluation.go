@@ -0,0 +1,52 @@
+package ratelimiter
+
+const EvaluationPolicyTokenThenIP = "token_then_ip"
+const EvaluationPolicyToken = "token"
+const EvaluationPolicyIP = "ip"
+const EvaluationPolicyBoth = "both"
+
+// RateLimitCheck is one of the limits applied to a request. Checks with a Scope, like the route and engine rules, keep
+// counters apart from the ones of the same key without it.
+type RateLimitCheck struct {
+	KeyType    string
+	Key        string
+	RuleName   string
+	RateConfig *RateConfig
+	Scope      string
+}
+
+func (c *RateLimitCheck) getStorageKeyType() string {
+	if c.Scope == "" {
+		return c.KeyType
+	}
+	return c.Scope + "-" + c.KeyType
+}
+
+func (c *LimiterConfig) GetEvaluationPolicy() string {
+	if c.EvaluationPolicy == "" {
+		return EvaluationPolicyTokenThenIP
+	}
+	return c.EvaluationPolicy
+}
+
+// MostRestrictiveDecision returns the denied decision that blocks for longer or, when every one allows, the one with the fewest
+// remaining requests. Nil decisions are skipped.
+func MostRestrictiveDecision(decisions []*RateLimitDecision) *RateLimitDecision {
+	var selected *RateLimitDecision
+	for _, decision := range decisions {
+		if decision == nil {
+			continue
+		}
+		switch {
+		case selected == nil:
+			selected = decision
+		case !decision.Allowed && selected.Allowed:
+			selected = decision
+		case !decision.Allowed && !selected.Allowed && decision.ResetAt.After(selected.ResetAt):
+			selected = decision
+		case decision.Allowed && selected.Allowed && decision.Remaining < selected.Remaining:
+			selected = decision
+		}
+	}
+	return selected
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,7 +13,7 @@ var StFormat = "2006-01-02 15:04:05"
 func PrintfD(config *LimiterConfig, format string, keyType string, key string, a ...any) (n int, err error) {
 	if config.Debug {
 		timeString := time.Now().UTC().Format(StFormat)
-		args := []any{timeString, keyType, key}
+		args := []any{timeString, keyType, config.GetRedactedKey(keyType, key)}
 		args = append(args, a...)
 		return fmt.Printf("%s [RATE LIMITER][%s][%s] "+format+"\n", args...)
 	}
@@ -22,15 +23,20 @@ func PrintfD(config *LimiterConfig, format string, keyType string, key string, a
 
 func PrintfWD(config *LimiterConfig, format string, a ...any) (n int, err error) {
 	if config.Debug {
-		timeString := time.Now().UTC().Format(StFormat)
-		args := []any{timeString}
-		args = append(args, a...)
-		return fmt.Printf("%s [RATE LIMITER] "+format+"\n", args...)
+		return PrintfW(format, a...)
 	}
 
 	return 0, nil
 }
 
+// PrintfW prints even without debug, for the configuration problems that would otherwise go unnoticed.
+func PrintfW(format string, a ...any) (n int, err error) {
+	timeString := time.Now().UTC().Format(StFormat)
+	args := []any{timeString}
+	args = append(args, a...)
+	return fmt.Printf("%s [RATE LIMITER] "+format+"\n", args...)
+}
+
 func GetBlockTime(block *time.Time) float64 {
 	return time.Until(*block).Seconds()
 }
@@ -75,3 +81,101 @@ func GetEnvLargeint(key string) (int64, bool) {
 	}
 	return parsed, true
 }
+
+func GetEnvList(key string) ([]string, bool) {
+	value, encontrou := os.LookupEnv(key)
+	if !encontrou {
+		return nil, false
+	}
+	values := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			values = append(values, item)
+		}
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values, true
+}
+
+func GetEnvWindowLimits(key string) ([]*WindowLimit, bool) {
+	value, encontrou := os.LookupEnv(key)
+	if !encontrou {
+		return nil, false
+	}
+	if value == "" {
+		return nil, false
+	}
+	limits, err := ParseWindowLimits(value)
+	if err != nil {
+		return nil, false
+	}
+	return limits, true
+}
+
+func ParseWindowLimits(value string) ([]*WindowLimit, error) {
+	limits := []*WindowLimit{}
+	for _, item := range strings.Split(value, ",") {
+		pair := strings.SplitN(strings.TrimSpace(item), "/", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid window limit \"%s\": expected <max requests>/<window milliseconds>", item)
+		}
+		maxRequests, err := strconv.ParseInt(pair[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		windowMilliseconds, err := strconv.ParseInt(pair[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if windowMilliseconds <= 0 {
+			return nil, fmt.Errorf("invalid window limit \"%s\": window must be positive", item)
+		}
+		limits = append(limits, &WindowLimit{MaxRequests: maxRequests, WindowMilliseconds: windowMilliseconds})
+	}
+	return limits, nil
+}
+
+func FormatWindowLimits(limits []*WindowLimit) string {
+	items := []string{}
+	for _, limit := range limits {
+		items = append(items, fmt.Sprintf("%d/%d", limit.MaxRequests, limit.WindowMilliseconds))
+	}
+	return strings.Join(items, ",")
+}
+
+func GetEnvTokenSources(key string) ([]*TokenSource, bool) {
+	value, encontrou := os.LookupEnv(key)
+	if !encontrou {
+		return nil, false
+	}
+	if value == "" {
+		return nil, false
+	}
+	sources, err := ParseTokenSources(value)
+	if err != nil {
+		return nil, false
+	}
+	return sources, true
+}
+
+func ParseTokenSources(value string) ([]*TokenSource, error) {
+	sources := []*TokenSource{}
+	for _, item := range strings.Split(value, ",") {
+		sourceType, name, _ := strings.Cut(strings.TrimSpace(item), ":")
+		sourceType = strings.ToLower(sourceType)
+		switch sourceType {
+		case TokenSourceHeader, TokenSourceQuery, TokenSourceCookie:
+			if name == "" {
+				return nil, fmt.Errorf("invalid token source \"%s\": expected %s:<name>", item, sourceType)
+			}
+		case TokenSourceBearer:
+		default:
+			return nil, fmt.Errorf("invalid token source \"%s\": expected header, bearer, query or cookie", item)
+		}
+		sources = append(sources, &TokenSource{Type: sourceType, Name: name})
+	}
+	return sources, nil
+}
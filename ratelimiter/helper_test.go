@@ -38,6 +38,23 @@ func (suite *UtilsTestSuite) TestDebugPrintf_DebugTrue() {
 	assert.Regexp(suite.T(), outputRegex, output)
 }
 
+func (suite *UtilsTestSuite) TestDebugPrintf_TokenRedacted() {
+	rateLimiterConfig := &LimiterConfig{
+		Debug:           true,
+		TokenHashSecret: "secret",
+	}
+	outputRegex := regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} \[RATE LIMITER]\[TOKEN\]\[9946dad4e00e913fc8be8e5d3f7e110a4a9e832f83fb09c345285d78638d8a0e\] Test message\n$`)
+
+	output, err := captureOutput(func() error {
+		_, err := PrintfD(rateLimiterConfig, "Test message", "TOKEN", "abc")
+		return err
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Regexp(suite.T(), outputRegex, output)
+	assert.NotContains(suite.T(), output, "[abc]")
+}
+
 func (suite *UtilsTestSuite) TestDebugPrintf_DebugFalse() {
 	rateLimiterConfig := &LimiterConfig{
 		Debug: false,
@@ -89,6 +106,20 @@ func (s *UtilsTestSuite) TestDebugPrintfWithoutKey_DebugFalse() {
 	assert.Empty(s.T(), output)
 }
 
+func (s *UtilsTestSuite) TestPrintfWithoutKey() {
+	message := "Test message %d"
+	messageParam := 1
+	outputRegex := regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} \[RATE LIMITER] Test message 1\n$`)
+
+	output, err := captureOutput(func() error {
+		_, err := PrintfW(message, messageParam)
+		return err
+	})
+
+	assert.Nil(s.T(), err)
+	assert.Regexp(s.T(), outputRegex, output)
+}
+
 func (s *UtilsTestSuite) TestGetRemainingBlockTime() {
 	block := time.Now().Add(time.Second * 6)
 	diff := GetBlockTime(&block)
@@ -179,6 +210,59 @@ func (s *UtilsTestSuite) TestGetInt64Env_InvalidValue() {
 	assert.Equal(s.T(), int64(0), value)
 }
 
+func (s *UtilsTestSuite) TestGetWindowLimitsEnv() {
+	os.Setenv("MY_ENV", "20/1000, 500/60000,100000/86400000")
+	value, ok := GetEnvWindowLimits("MY_ENV")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), []*WindowLimit{
+		{MaxRequests: 20, WindowMilliseconds: 1000},
+		{MaxRequests: 500, WindowMilliseconds: 60000},
+		{MaxRequests: 100000, WindowMilliseconds: 86400000},
+	}, value)
+}
+
+func (s *UtilsTestSuite) TestGetWindowLimitsEnv_NoValue() {
+	value, ok := GetEnvWindowLimits("MY_ANOTHER_ENV")
+	assert.False(s.T(), ok)
+	assert.Nil(s.T(), value)
+}
+
+func (s *UtilsTestSuite) TestGetWindowLimitsEnv_InvalidValue() {
+	for _, invalid := range []string{"20", "20/abc", "abc/1000", "20/0"} {
+		os.Setenv("MY_ENV", invalid)
+		value, ok := GetEnvWindowLimits("MY_ENV")
+		assert.False(s.T(), ok)
+		assert.Nil(s.T(), value)
+	}
+}
+
+func (s *UtilsTestSuite) TestFormatWindowLimits() {
+	limits := []*WindowLimit{
+		{MaxRequests: 20, WindowMilliseconds: 1000},
+		{MaxRequests: 500, WindowMilliseconds: 60000},
+	}
+	assert.Equal(s.T(), "20/1000,500/60000", FormatWindowLimits(limits))
+}
+
+func (s *UtilsTestSuite) TestGetTokenSourcesEnv() {
+	os.Setenv("MY_ENV", "Header:X-API-Key,bearer")
+	value, ok := GetEnvTokenSources("MY_ENV")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), []*TokenSource{
+		{Type: TokenSourceHeader, Name: "X-API-Key"},
+		{Type: TokenSourceBearer},
+	}, value)
+}
+
+func (s *UtilsTestSuite) TestGetTokenSourcesEnv_InvalidValue() {
+	for _, invalid := range []string{"header", "query:", "form:token", "header:X-API-Key,"} {
+		os.Setenv("MY_ENV", invalid)
+		value, ok := GetEnvTokenSources("MY_ENV")
+		assert.False(s.T(), ok, invalid)
+		assert.Nil(s.T(), value)
+	}
+}
+
 func captureOutput(f func() error) (string, error) {
 	orig := os.Stdout
 	r, w, _ := os.Pipe()
@@ -0,0 +1,9 @@
+package ratelimiter
+
+import "net/http"
+
+// KeyExtractor derives the limiter key from a request. An empty key means the extractor does not apply, and a nil
+// rateConfig uses IP for the "IP" key type and Token for the others.
+type KeyExtractor interface {
+	Extract(r *http.Request, config *LimiterConfig) (keyType string, key string, rateConfig *RateConfig)
+}
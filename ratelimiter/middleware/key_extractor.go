@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/danielzinhors/rate-limiter/ratelimiter"
+)
+
+type KeyExtractor = ratelimiter.KeyExtractor
+
+// KeyExtractorFunc lets a plain function be used as a KeyExtractor.
+type KeyExtractorFunc func(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig)
+
+func (f KeyExtractorFunc) Extract(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+	return f(r, config)
+}
+
+type ipKeyExtractor struct{}
+
+type tokenKeyExtractor struct{}
+
+// unknownTokenIPKeyExtractor limits by IP the requests whose token the UnknownTokenPolicy sends to the IP limits.
+type unknownTokenIPKeyExtractor struct{}
+
+type valueKeyExtractor struct {
+	keyType    string
+	rateConfig *ratelimiter.RateConfig
+	value      func(r *http.Request) string
+}
+
+type compositeKeyExtractor struct {
+	keyType    string
+	rateConfig *ratelimiter.RateConfig
+	extractors []KeyExtractor
+}
+
+func NewIPKeyExtractor() *ipKeyExtractor {
+	return &ipKeyExtractor{}
+}
+
+// NewTokenKeyExtractor reads the token from the sources in LimiterConfig.TokenSources, skipping unknown tokens unless
+// LimiterConfig.UnknownTokenPolicy allows them.
+func NewTokenKeyExtractor() *tokenKeyExtractor {
+	return &tokenKeyExtractor{}
+}
+
+// NewHeaderKeyExtractor uses the token configuration, custom tokens included, when rateConfig is nil.
+func NewHeaderKeyExtractor(keyType string, header string, rateConfig *ratelimiter.RateConfig) *valueKeyExtractor {
+	return &valueKeyExtractor{keyType: keyType, rateConfig: rateConfig, value: func(r *http.Request) string {
+		return r.Header.Get(header)
+	}}
+}
+
+func NewQueryKeyExtractor(keyType string, parameter string, rateConfig *ratelimiter.RateConfig) *valueKeyExtractor {
+	return &valueKeyExtractor{keyType: keyType, rateConfig: rateConfig, value: func(r *http.Request) string {
+		return r.URL.Query().Get(parameter)
+	}}
+}
+
+func NewCookieKeyExtractor(keyType string, cookie string, rateConfig *ratelimiter.RateConfig) *valueKeyExtractor {
+	return &valueKeyExtractor{keyType: keyType, rateConfig: rateConfig, value: func(r *http.Request) string {
+		value, err := r.Cookie(cookie)
+		if err != nil {
+			return ""
+		}
+		return value.Value
+	}}
+}
+
+// NewCompositeKeyExtractor joins the keys of every extractor, and applies only when all of them do.
+// The rate config of the first extractor is used when rateConfig is nil.
+func NewCompositeKeyExtractor(keyType string, rateConfig *ratelimiter.RateConfig, extractors ...KeyExtractor) *compositeKeyExtractor {
+	return &compositeKeyExtractor{keyType: keyType, rateConfig: rateConfig, extractors: extractors}
+}
+
+func (e *ipKeyExtractor) Extract(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+	ip := config.GetClientIP(r)
+	rateConfig, _ := config.GetRateLimiterRateConfigForIP(ip)
+	return "IP", rateConfig.NormalizeIP(ip), rateConfig
+}
+
+func (e *tokenKeyExtractor) Extract(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+	token := config.GetToken(r)
+	if token == "" || config.IsUnknownToken(token) {
+		return "", "", nil
+	}
+	rateConfig, _ := config.GetRateLimiterRateConfigForToken(token)
+	return "TOKEN", token, rateConfig
+}
+
+func (e *unknownTokenIPKeyExtractor) Extract(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+	token := config.GetToken(r)
+	if token == "" || !config.IsUnknownToken(token) || config.GetUnknownTokenPolicy() != ratelimiter.UnknownTokenPolicyIP {
+		return "", "", nil
+	}
+	return NewIPKeyExtractor().Extract(r, config)
+}
+
+func (e *valueKeyExtractor) Extract(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+	key := e.value(r)
+	if key == "" {
+		return "", "", nil
+	}
+	if e.rateConfig != nil {
+		return e.keyType, key, e.rateConfig
+	}
+	rateConfig, _ := config.GetRateLimiterRateConfigForToken(key)
+	return e.keyType, key, rateConfig
+}
+
+func (e *compositeKeyExtractor) Extract(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+	keys := []string{}
+	rateConfig := e.rateConfig
+
+	for _, extractor := range e.extractors {
+		_, key, extractorRateConfig := extractor.Extract(r, config)
+		if key == "" {
+			return "", "", nil
+		}
+		if rateConfig == nil {
+			rateConfig = extractorRateConfig
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return "", "", nil
+	}
+
+	return e.keyType, strings.Join(keys, "|"), rateConfig
+}
+
+var defaultKeyExtractors = []KeyExtractor{NewTokenKeyExtractor(), NewIPKeyExtractor()}
+
+// getKeyExtractors narrows the default extractors to the evaluation policy. Custom extractors are always used as configured.
+func getKeyExtractors(config *ratelimiter.LimiterConfig) []KeyExtractor {
+	if len(config.KeyExtractors) > 0 {
+		return config.KeyExtractors
+	}
+
+	switch config.GetEvaluationPolicy() {
+	case ratelimiter.EvaluationPolicyToken:
+		// Without the IP, an unknown token the policy does not count would not be limited at all.
+		return []KeyExtractor{NewTokenKeyExtractor(), &unknownTokenIPKeyExtractor{}}
+	case ratelimiter.EvaluationPolicyIP:
+		return []KeyExtractor{NewIPKeyExtractor()}
+	default:
+		return defaultKeyExtractors
+	}
+}
+
+func extractKey(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+	for _, extractor := range getKeyExtractors(config) {
+		keyType, key, rateConfig := extractor.Extract(r, config)
+		if key != "" {
+			return keyType, key, rateConfig
+		}
+	}
+
+	return "", "", nil
+}
+
+// extractChecks returns the limits of the first extractor that applies or, under EvaluationPolicyBoth, of every one that does.
+// The rules matching the request, or else the first matching route rule, replace those limits with their own.
+func extractChecks(r *http.Request, config *ratelimiter.LimiterConfig) []*ratelimiter.RateLimitCheck {
+	return extractChecksForRules(r, config, config.MatchRules(r))
+}
+
+func extractChecksForRules(r *http.Request, config *ratelimiter.LimiterConfig, rules []*ratelimiter.Rule) []*ratelimiter.RateLimitCheck {
+	if checks := extractRuleChecks(r, config, rules); len(checks) > 0 {
+		return checks
+	}
+	if rule := config.GetRouteRule(r.Method, r.URL.Path); rule != nil {
+		return extractRouteChecks(r, config, rule)
+	}
+	return extractPolicyChecks(r, config)
+}
+
+func extractPolicyChecks(r *http.Request, config *ratelimiter.LimiterConfig) []*ratelimiter.RateLimitCheck {
+	checks := []*ratelimiter.RateLimitCheck{}
+
+	if config.GetEvaluationPolicy() != ratelimiter.EvaluationPolicyBoth {
+		keyType, key, rateConfig := extractKey(r, config)
+		if key != "" {
+			checks = append(checks, newRateLimitCheck(config, keyType, key, rateConfig))
+		}
+		return checks
+	}
+
+	for _, extractor := range getKeyExtractors(config) {
+		keyType, key, rateConfig := extractor.Extract(r, config)
+		if key != "" {
+			checks = append(checks, newRateLimitCheck(config, keyType, key, rateConfig))
+		}
+	}
+
+	return checks
+}
+
+// extractRuleChecks uses the key template of each rule or, without one, the keys of the evaluation policy.
+func extractRuleChecks(r *http.Request, config *ratelimiter.LimiterConfig, rules []*ratelimiter.Rule) []*ratelimiter.RateLimitCheck {
+	checks := []*ratelimiter.RateLimitCheck{}
+	for _, rule := range rules {
+		if rule.Key != "" {
+			if check := rule.NewCheck(r, config); check != nil {
+				checks = append(checks, check)
+			}
+			continue
+		}
+		for _, check := range extractPolicyChecks(r, config) {
+			checks = append(checks, newScopedRateLimitCheck(check, rule.GetRuleName(), rule.GetScope(), rule.Rate))
+		}
+	}
+	return checks
+}
+
+// extractRouteChecks applies the rule to the key of its KeyType or, without one, to the keys of the evaluation policy.
+func extractRouteChecks(r *http.Request, config *ratelimiter.LimiterConfig, rule *ratelimiter.RouteRule) []*ratelimiter.RateLimitCheck {
+	checks := []*ratelimiter.RateLimitCheck{}
+	if rule.KeyType == "" {
+		for _, check := range extractPolicyChecks(r, config) {
+			checks = append(checks, newRouteRateLimitCheck(rule, check))
+		}
+		return checks
+	}
+
+	extractors := defaultKeyExtractors
+	if len(config.KeyExtractors) > 0 {
+		extractors = config.KeyExtractors
+	}
+	for _, extractor := range extractors {
+		keyType, key, rateConfig := extractor.Extract(r, config)
+		if key != "" && strings.EqualFold(keyType, rule.KeyType) {
+			checks = append(checks, newRouteRateLimitCheck(rule, newRateLimitCheck(config, keyType, key, rateConfig)))
+			break
+		}
+	}
+
+	return checks
+}
+
+func newRouteRateLimitCheck(rule *ratelimiter.RouteRule, check *ratelimiter.RateLimitCheck) *ratelimiter.RateLimitCheck {
+	return newScopedRateLimitCheck(check, "routes."+rule.GetName(), rule.GetScope(), rule.Rate)
+}
+
+func newScopedRateLimitCheck(check *ratelimiter.RateLimitCheck, ruleName string, scope string, rateConfig *ratelimiter.RateConfig) *ratelimiter.RateLimitCheck {
+	check.RuleName = ruleName
+	check.Scope = scope
+	if rateConfig != nil {
+		check.RateConfig = rateConfig
+	}
+	return check
+}
+
+func newRateLimitCheck(config *ratelimiter.LimiterConfig, keyType string, key string, rateConfig *ratelimiter.RateConfig) *ratelimiter.RateLimitCheck {
+	if rateConfig == nil && keyType == "IP" {
+		rateConfig = config.IP
+	} else if rateConfig == nil {
+		rateConfig = config.Token
+	}
+	return &ratelimiter.RateLimitCheck{KeyType: keyType, Key: key, RuleName: getRuleName(config, keyType, key, rateConfig), RateConfig: rateConfig}
+}
+
+func getRuleName(config *ratelimiter.LimiterConfig, keyType string, key string, rateConfig *ratelimiter.RateConfig) string {
+	switch {
+	case rateConfig != nil && rateConfig == config.IP:
+		return "ip"
+	case rateConfig != nil && rateConfig == config.Token:
+		return "token"
+	case config.CustomTokens != nil && rateConfig != nil && (*config.CustomTokens)[key] == rateConfig:
+		return "tokens." + config.HashToken(key)
+	case keyType == "IP" && config.CustomIPs != nil && rateConfig != nil:
+		for customIP, customRateConfig := range *config.CustomIPs {
+			if customRateConfig == rateConfig {
+				return "ips." + customIP
+			}
+		}
+		return strings.ToLower(keyType)
+	default:
+		return strings.ToLower(keyType)
+	}
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielzinhors/rate-limiter/ratelimiter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type KeyExtractorTestSuite struct {
+	suite.Suite
+	config *ratelimiter.LimiterConfig
+}
+
+func TestKeyExtractorTestSuite(t *testing.T) {
+	suite.Run(t, new(KeyExtractorTestSuite))
+}
+
+func (s *KeyExtractorTestSuite) SetupTest() {
+	s.config = &ratelimiter.LimiterConfig{
+		IP:    &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		Token: &ratelimiter.RateConfig{MaxRequestsPerSecond: 20},
+		CustomTokens: &map[string]*ratelimiter.RateConfig{
+			"abc": {MaxRequestsPerSecond: 30},
+		},
+	}
+}
+
+func (s *KeyExtractorTestSuite) TestIPKeyExtractor() {
+	request := httptest.NewRequest("GET", "http://testing", nil)
+
+	keyType, key, rateConfig := NewIPKeyExtractor().Extract(request, s.config)
+	assert.Equal(s.T(), "IP", keyType)
+	assert.Equal(s.T(), "192.0.2.1", key)
+	assert.Equal(s.T(), s.config.IP, rateConfig)
+}
+
+func (s *KeyExtractorTestSuite) TestIPKeyExtractor_Prefix() {
+	s.config.IP.IPv4PrefixLength = 24
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.RemoteAddr = "[::ffff:198.51.100.23]:1234"
+
+	_, key, _ := NewIPKeyExtractor().Extract(request, s.config)
+	assert.Equal(s.T(), "198.51.100.0/24", key)
+}
+
+func (s *KeyExtractorTestSuite) TestIPKeyExtractor_CustomIP() {
+	officeConfig := &ratelimiter.RateConfig{MaxRequestsPerSecond: 1000, IPv4PrefixLength: 24}
+	s.config.CustomIPs = &map[string]*ratelimiter.RateConfig{"192.0.2.0/24": officeConfig}
+	request := httptest.NewRequest("GET", "http://testing", nil)
+
+	keyType, key, rateConfig := NewIPKeyExtractor().Extract(request, s.config)
+	assert.Equal(s.T(), "IP", keyType)
+	assert.Equal(s.T(), "192.0.2.0/24", key)
+	assert.Equal(s.T(), officeConfig, rateConfig)
+	assert.Equal(s.T(), "ips.192.0.2.0/24", getRuleName(s.config, keyType, key, rateConfig))
+}
+
+func (s *KeyExtractorTestSuite) TestTokenKeyExtractor() {
+	request := httptest.NewRequest("GET", "http://testing", nil)
+
+	_, key, _ := NewTokenKeyExtractor().Extract(request, s.config)
+	assert.Equal(s.T(), "", key)
+
+	request.Header.Set("API_KEY", "abc")
+	keyType, key, rateConfig := NewTokenKeyExtractor().Extract(request, s.config)
+	assert.Equal(s.T(), "TOKEN", keyType)
+	assert.Equal(s.T(), "abc", key)
+	assert.Equal(s.T(), (*s.config.CustomTokens)["abc"], rateConfig)
+}
+
+func (s *KeyExtractorTestSuite) TestTokenKeyExtractor_UnknownTokenPolicy() {
+	s.config.UnknownTokenPolicy = ratelimiter.UnknownTokenPolicyIP
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("API_KEY", "random")
+
+	_, key, _ := NewTokenKeyExtractor().Extract(request, s.config)
+	assert.Equal(s.T(), "", key)
+
+	keyType, key, _ := extractKey(request, s.config)
+	assert.Equal(s.T(), "IP", keyType)
+	assert.Equal(s.T(), "192.0.2.1", key)
+
+	request.Header.Set("API_KEY", "abc")
+	keyType, key, _ = extractKey(request, s.config)
+	assert.Equal(s.T(), "TOKEN", keyType)
+	assert.Equal(s.T(), "abc", key)
+}
+
+func (s *KeyExtractorTestSuite) TestHeaderQueryCookieKeyExtractors() {
+	tenantConfig := &ratelimiter.RateConfig{MaxRequestsPerSecond: 50}
+	request := httptest.NewRequest("GET", "http://testing?user=42", nil)
+	request.Header.Set("X-Tenant", "acme")
+	request.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	keyType, key, rateConfig := NewHeaderKeyExtractor("TENANT", "X-Tenant", tenantConfig).Extract(request, s.config)
+	assert.Equal(s.T(), "TENANT", keyType)
+	assert.Equal(s.T(), "acme", key)
+	assert.Equal(s.T(), tenantConfig, rateConfig)
+
+	keyType, key, rateConfig = NewQueryKeyExtractor("USER", "user", nil).Extract(request, s.config)
+	assert.Equal(s.T(), "USER", keyType)
+	assert.Equal(s.T(), "42", key)
+	assert.Equal(s.T(), s.config.Token, rateConfig)
+
+	keyType, key, rateConfig = NewCookieKeyExtractor("SESSION", "session", nil).Extract(request, s.config)
+	assert.Equal(s.T(), "SESSION", keyType)
+	assert.Equal(s.T(), "abc", key)
+	assert.Equal(s.T(), (*s.config.CustomTokens)["abc"], rateConfig)
+
+	_, key, rateConfig = NewCookieKeyExtractor("SESSION", "missing", nil).Extract(request, s.config)
+	assert.Equal(s.T(), "", key)
+	assert.Nil(s.T(), rateConfig)
+}
+
+func (s *KeyExtractorTestSuite) TestCompositeKeyExtractor() {
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("X-Tenant", "acme")
+
+	extractor := NewCompositeKeyExtractor("TENANT_IP", nil, NewHeaderKeyExtractor("TENANT", "X-Tenant", s.config.Token), NewIPKeyExtractor())
+
+	keyType, key, rateConfig := extractor.Extract(request, s.config)
+	assert.Equal(s.T(), "TENANT_IP", keyType)
+	assert.Equal(s.T(), "acme|192.0.2.1", key)
+	assert.Equal(s.T(), s.config.Token, rateConfig)
+
+	request.Header.Del("X-Tenant")
+	_, key, _ = extractor.Extract(request, s.config)
+	assert.Equal(s.T(), "", key)
+}
+
+func (s *KeyExtractorTestSuite) TestExtractKey_CustomExtractor() {
+	subdomainConfig := &ratelimiter.RateConfig{MaxRequestsPerSecond: 5}
+	s.config.KeyExtractors = []KeyExtractor{
+		KeyExtractorFunc(func(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+			subdomain, _, found := strings.Cut(r.Host, ".")
+			if !found {
+				return "", "", nil
+			}
+			return "TENANT", subdomain, subdomainConfig
+		}),
+		NewIPKeyExtractor(),
+	}
+
+	keyType, key, rateConfig := extractKey(httptest.NewRequest("GET", "http://acme.example.com", nil), s.config)
+	assert.Equal(s.T(), "TENANT", keyType)
+	assert.Equal(s.T(), "acme", key)
+	assert.Equal(s.T(), subdomainConfig, rateConfig)
+	assert.Equal(s.T(), "tenant", getRuleName(s.config, keyType, key, rateConfig))
+
+	keyType, key, rateConfig = extractKey(httptest.NewRequest("GET", "http://localhost", nil), s.config)
+	assert.Equal(s.T(), "IP", keyType)
+	assert.Equal(s.T(), "192.0.2.1", key)
+	assert.Equal(s.T(), "ip", getRuleName(s.config, keyType, key, rateConfig))
+}
+
+func (s *KeyExtractorTestSuite) TestExtractChecks_EvaluationPolicy() {
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("API_KEY", "abc")
+
+	checks := extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 1)
+	assert.Equal(s.T(), "TOKEN", checks[0].KeyType)
+
+	s.config.EvaluationPolicy = ratelimiter.EvaluationPolicyBoth
+	checks = extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 2)
+	assert.Equal(s.T(), "tokens."+s.config.HashToken("abc"), checks[0].RuleName)
+	assert.Equal(s.T(), "192.0.2.1", checks[1].Key)
+
+	s.config.EvaluationPolicy = ratelimiter.EvaluationPolicyToken
+	assert.Empty(s.T(), extractChecks(httptest.NewRequest("GET", "http://testing", nil), s.config))
+
+	s.config.UnknownTokenPolicy = ratelimiter.UnknownTokenPolicyIP
+	unknownRequest := httptest.NewRequest("GET", "http://testing", nil)
+	unknownRequest.Header.Set("API_KEY", "random")
+	checks = extractChecks(unknownRequest, s.config)
+	assert.Len(s.T(), checks, 1)
+	assert.Equal(s.T(), "IP", checks[0].KeyType)
+	assert.Empty(s.T(), extractChecks(httptest.NewRequest("GET", "http://testing", nil), s.config))
+	s.config.UnknownTokenPolicy = ""
+
+	s.config.EvaluationPolicy = ratelimiter.EvaluationPolicyIP
+	checks = extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 1)
+	assert.Equal(s.T(), "IP", checks[0].KeyType)
+}
+
+func (s *KeyExtractorTestSuite) TestExtractChecks_RouteRule() {
+	loginConfig := &ratelimiter.RateConfig{MaxRequestsPerSecond: 2}
+	s.config.Routes = []*ratelimiter.RouteRule{
+		{Name: "login", Path: "/login", Methods: []string{"POST"}, KeyType: "ip", Rate: loginConfig},
+		{Path: "/users/{id}"},
+	}
+	request := httptest.NewRequest("POST", "http://testing/login", nil)
+	request.Header.Set("API_KEY", "abc")
+
+	checks := extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 1)
+	assert.Equal(s.T(), "IP", checks[0].KeyType)
+	assert.Equal(s.T(), "192.0.2.1", checks[0].Key)
+	assert.Equal(s.T(), "routes.login", checks[0].RuleName)
+	assert.Equal(s.T(), "ROUTE-login", checks[0].Scope)
+	assert.Equal(s.T(), loginConfig, checks[0].RateConfig)
+
+	request = httptest.NewRequest("GET", "http://testing/users/42", nil)
+	request.Header.Set("API_KEY", "abc")
+	checks = extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 1)
+	assert.Equal(s.T(), "TOKEN", checks[0].KeyType)
+	assert.Equal(s.T(), "routes./users/{id}", checks[0].RuleName)
+	assert.Equal(s.T(), (*s.config.CustomTokens)["abc"], checks[0].RateConfig)
+
+	request = httptest.NewRequest("GET", "http://testing/login", nil)
+	checks = extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 1)
+	assert.Equal(s.T(), "ip", checks[0].RuleName)
+	assert.Equal(s.T(), "", checks[0].Scope)
+}
+
+func (s *KeyExtractorTestSuite) TestExtractChecks_Rules() {
+	noUserAgentConfig := &ratelimiter.RateConfig{MaxRequestsPerSecond: 1}
+	s.config.RuleMode = ratelimiter.RuleModeAllMatch
+	s.config.Rules = []*ratelimiter.Rule{
+		{Name: "no-user-agent", Match: ratelimiter.RuleMatch{Headers: map[string]string{"User-Agent": ""}}, Rate: noUserAgentConfig},
+		{Name: "tenant", Key: `{{.Header "X-Tenant"}}`, Rate: &ratelimiter.RateConfig{MaxRequestsPerSecond: 5}},
+	}
+	s.config.Routes = []*ratelimiter.RouteRule{{Path: "/login"}}
+	request := httptest.NewRequest("GET", "http://testing/login", nil)
+	request.Header.Set("API_KEY", "abc")
+	request.Header.Set("X-Tenant", "acme")
+
+	checks := extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 2)
+	assert.Equal(s.T(), "TOKEN", checks[0].KeyType)
+	assert.Equal(s.T(), "rules.no-user-agent", checks[0].RuleName)
+	assert.Equal(s.T(), "RULE-no-user-agent", checks[0].Scope)
+	assert.Equal(s.T(), noUserAgentConfig, checks[0].RateConfig)
+	assert.Equal(s.T(), "RULE", checks[1].KeyType)
+	assert.Equal(s.T(), "acme", checks[1].Key)
+
+	request.Header.Set("User-Agent", "curl/8.0")
+	request.Header.Del("X-Tenant")
+	checks = extractChecks(request, s.config)
+	assert.Len(s.T(), checks, 1)
+	assert.Equal(s.T(), "routes./login", checks[0].RuleName)
+}
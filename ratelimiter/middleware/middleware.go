@@ -2,14 +2,13 @@ package middleware
 
 import (
 	"context"
-	"net"
 	"net/http"
-	"time"
 
 	"github.com/danielzinhors/rate-limiter/ratelimiter"
+	"github.com/danielzinhors/rate-limiter/ratelimiter/response_writer"
 )
 
-type rateLimiterCheckFunction = func(ctx context.Context, keyType string, key string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*time.Time, error)
+type rateLimiterCheckFunction = func(ctx context.Context, checks []*ratelimiter.RateLimitCheck, config *ratelimiter.LimiterConfig) ([]*ratelimiter.RateLimitDecision, error)
 
 func NewRateLimiter() func(next http.Handler) http.Handler {
 	return NewRateLimiterWithConfig(nil)
@@ -18,34 +17,62 @@ func NewRateLimiter() func(next http.Handler) http.Handler {
 func NewRateLimiterWithConfig(config *ratelimiter.LimiterConfig) func(next http.Handler) http.Handler {
 	config = ratelimiter.SetConfiguration(config)
 	return func(next http.Handler) http.Handler {
-		return rateLimiter(config, next, ratelimiter.CheckRateLimit)
+		return rateLimiter(config, next, ratelimiter.CheckRateLimitDecisions)
 	}
 }
 
 func rateLimiter(config *ratelimiter.LimiterConfig, next http.Handler, checkRateLimitFn rateLimiterCheckFunction) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var block *time.Time
-		var err error
-
-		token := r.Header.Get("API_KEY")
-		if token != "" {
-			tokenConfig, _ := config.GetRateLimiterRateConfigForToken(token)
-			block, err = checkRateLimitFn(r.Context(), "TOKEN", token, config, tokenConfig)
-		} else {
-			host, _, _ := net.SplitHostPort(r.RemoteAddr)
-			block, err = checkRateLimitFn(r.Context(), "IP", host, config, config.IP)
+		switch config.GetAccess(config.GetClientIP(r), config.GetToken(r)) {
+		case ratelimiter.AccessDenied:
+			config.GetRequestResponseWriter(r).WriteDeniedResponse(w, r)
+			return
+		case ratelimiter.AccessAllowed:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token := config.GetToken(r); token != "" && config.GetUnknownTokenPolicy() == ratelimiter.UnknownTokenPolicyReject && !config.IsKnownToken(token) {
+			config.GetRequestResponseWriter(r).WriteUnauthorizedResponse(w, r)
+			return
+		}
+
+		rules := config.MatchRules(r)
+		checks := extractChecksForRules(r, config, rules)
+		if len(checks) == 0 {
+			next.ServeHTTP(w, r)
+			return
 		}
 
+		decisions, err := checkRateLimitFn(r.Context(), checks, config)
+
+		responseWriter := config.GetRequestResponseWriter(r)
+
 		if err != nil {
-			config.ResponseWriter.WriteError(&w, err)
+			responseWriter.WriteErrorResponse(w, r, &response_writer.RateLimitError{KeyType: checks[0].KeyType, Key: config.GetRedactedKey(checks[0].KeyType, checks[0].Key), RuleName: checks[0].RuleName, Err: err})
 			return
 		}
 
-		if block != nil {
-			config.ResponseWriter.WriteResponse(&w)
+		decision := ratelimiter.MostRestrictiveDecision(decisions)
+		if decision != nil && len(rules) > 0 {
+			decision.MatchedRules = getRuleNames(rules)
+		}
+
+		response_writer.WriteRateLimitHeaders(w, decision, config.GetHeadersMode())
+
+		if decision != nil && !decision.Allowed {
+			responseWriter.WriteBlockedResponse(w, r, decision)
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+func getRuleNames(rules []*ratelimiter.Rule) []string {
+	names := []string{}
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
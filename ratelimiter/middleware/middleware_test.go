@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/danielzinhors/rate-limiter/ratelimiter"
+	"github.com/danielzinhors/rate-limiter/ratelimiter/adapters"
 	"github.com/danielzinhors/rate-limiter/ratelimiter/mocks"
+	"github.com/danielzinhors/rate-limiter/ratelimiter/response_writer"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -23,6 +25,21 @@ type MiddlewareTestSuite struct {
 	responseWriterMock *mocks.MockRateLimiterResponseWriter
 }
 
+// checkEach runs a single key check function for every check, like CheckRateLimitDecisions does in one batch.
+func checkEach(checkFn func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error)) rateLimiterCheckFunction {
+	return func(ctx context.Context, checks []*ratelimiter.RateLimitCheck, config *ratelimiter.LimiterConfig) ([]*ratelimiter.RateLimitDecision, error) {
+		decisions := []*ratelimiter.RateLimitDecision{}
+		for _, check := range checks {
+			decision, err := checkFn(ctx, check.KeyType, check.Key, check.RuleName, config, check.RateConfig)
+			if err != nil {
+				return nil, err
+			}
+			decisions = append(decisions, decision)
+		}
+		return decisions, nil
+	}
+}
+
 func TestMiddlewareTestSuite(t *testing.T) {
 	suite.Run(t, new(MiddlewareTestSuite))
 }
@@ -67,14 +84,14 @@ func (s *MiddlewareTestSuite) TestMiddleware_IPAllowed() {
 		w.Write([]byte("DONE"))
 	})
 
-	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*time.Time, error) {
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
 		return nil, nil
 	}
 
 	request := httptest.NewRequest("GET", "http://testing", nil)
 	recorder := httptest.NewRecorder()
 
-	rateLimiter(config, nextHandler, rateLimiterCheckFunction).ServeHTTP(recorder, request)
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
 
 	response := recorder.Result()
 	responseStatus := response.StatusCode
@@ -98,9 +115,8 @@ func (s *MiddlewareTestSuite) TestMiddleware_IPNotAllowed() {
 		w.Write([]byte("DONE"))
 	})
 
-	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*time.Time, error) {
-		block := time.Now().Add(time.Millisecond * 100)
-		return &block, nil
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return &ratelimiter.RateLimitDecision{Allowed: false, ResetAt: time.Now().Add(time.Millisecond * 100)}, nil
 	}
 
 	request := httptest.NewRequest("GET", "http://testing", nil)
@@ -112,7 +128,7 @@ func (s *MiddlewareTestSuite) TestMiddleware_IPNotAllowed() {
 	})
 	config.ResponseWriter = s.responseWriterMock
 
-	rateLimiter(config, nextHandler, rateLimiterCheckFunction).ServeHTTP(recorder, request)
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
 
 	response := recorder.Result()
 	responseStatus := response.StatusCode
@@ -136,7 +152,7 @@ func (s *MiddlewareTestSuite) TestMiddleware_IPError() {
 		w.Write([]byte("DONE"))
 	})
 
-	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*time.Time, error) {
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
 		return nil, errors.New("error")
 	}
 
@@ -149,7 +165,7 @@ func (s *MiddlewareTestSuite) TestMiddleware_IPError() {
 	})
 	config.ResponseWriter = s.responseWriterMock
 
-	rateLimiter(config, nextHandler, rateLimiterCheckFunction).ServeHTTP(recorder, request)
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
 
 	response := recorder.Result()
 	responseStatus := response.StatusCode
@@ -174,7 +190,7 @@ func (s *MiddlewareTestSuite) TestMiddleware_TokenAllowed() {
 		w.Write([]byte("DONE"))
 	})
 
-	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*time.Time, error) {
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
 		return nil, nil
 	}
 
@@ -182,7 +198,7 @@ func (s *MiddlewareTestSuite) TestMiddleware_TokenAllowed() {
 	request.Header.Add("API_KEY", "123")
 	recorder := httptest.NewRecorder()
 
-	rateLimiter(config, nextHandler, rateLimiterCheckFunction).ServeHTTP(recorder, request)
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
 
 	response := recorder.Result()
 	responseStatus := response.StatusCode
@@ -192,3 +208,618 @@ func (s *MiddlewareTestSuite) TestMiddleware_TokenAllowed() {
 	assert.Equal(s.T(), 200, responseStatus)
 	assert.Equal(s.T(), "DONE", string(responseBody))
 }
+
+func (s *MiddlewareTestSuite) TestMiddleware_RequestResponseWriterCustomToken() {
+	config := &ratelimiter.LimiterConfig{
+		Token: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+		CustomTokens: &map[string]*ratelimiter.RateConfig{
+			"abc": {MaxRequestsPerSecond: 20, BlockTimeMilliseconds: 100},
+		},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte("DONE"))
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return &ratelimiter.RateLimitDecision{Allowed: false, Limit: 20, KeyType: keyType, Key: key, RuleName: ruleName}, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "abc")
+	recorder := httptest.NewRecorder()
+
+	requestResponseWriterMock := mocks.NewMockRateLimiterRequestResponseWriter(s.controller)
+	requestResponseWriterMock.EXPECT().WriteBlockedResponse(gomock.Any(), request, gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request, decision *ratelimiter.RateLimitDecision) {
+		assert.Equal(s.T(), "TOKEN", decision.KeyType)
+		assert.Equal(s.T(), "abc", decision.Key)
+		assert.Equal(s.T(), "tokens."+config.HashToken("abc"), decision.RuleName)
+		w.WriteHeader(429)
+	})
+	config.RequestResponseWriter = requestResponseWriterMock
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 429, recorder.Result().StatusCode)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_RateLimitHeaders() {
+	config := &ratelimiter.LimiterConfig{
+		IP: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return &ratelimiter.RateLimitDecision{Allowed: true, Limit: 10, Remaining: 9, WindowMilliseconds: 1000, ResetAt: time.Now().Add(time.Second)}, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	assert.Equal(s.T(), 200, response.StatusCode)
+	assert.Equal(s.T(), "10", response.Header.Get("RateLimit-Limit"))
+	assert.Equal(s.T(), "9", response.Header.Get("RateLimit-Remaining"))
+	assert.Equal(s.T(), "10;w=1", response.Header.Get("RateLimit-Policy"))
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_RateLimitHeadersLegacyDenied() {
+	config := &ratelimiter.LimiterConfig{
+		IP: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+		HeadersMode: ratelimiter.HeadersModeLegacy,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return &ratelimiter.RateLimitDecision{Allowed: false, Limit: 10, ResetAt: time.Now().Add(time.Second), RetryAfter: time.Second}, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	recorder := httptest.NewRecorder()
+
+	s.responseWriterMock.EXPECT().WriteResponse(gomock.Any()).Do(func(w *http.ResponseWriter) {
+		(*w).WriteHeader(429)
+	})
+	config.ResponseWriter = s.responseWriterMock
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	assert.Equal(s.T(), 429, response.StatusCode)
+	assert.Equal(s.T(), "10", response.Header.Get("X-RateLimit-Limit"))
+	assert.Equal(s.T(), "0", response.Header.Get("X-RateLimit-Remaining"))
+	assert.Equal(s.T(), "1", response.Header.Get("Retry-After"))
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_ContentNegotiation() {
+	contentNegotiator, err := response_writer.NewRateLimiterContentNegotiator(nil)
+	assert.Nil(s.T(), err)
+
+	config := &ratelimiter.LimiterConfig{
+		IP: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+		ResponseWriter:    s.responseWriterMock,
+		ContentNegotiator: contentNegotiator,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return &ratelimiter.RateLimitDecision{Allowed: false, Limit: 10, ResetAt: time.Now().Add(time.Second), RetryAfter: time.Second}, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("Accept", "application/problem+json")
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 429, response.StatusCode)
+	assert.Equal(s.T(), "application/problem+json; charset=utf-8", response.Header.Get("Content-Type"))
+	assert.Contains(s.T(), string(responseBody), `"retryAfter":1`)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_ContentNegotiationFallback() {
+	contentNegotiator, err := response_writer.NewRateLimiterContentNegotiator(nil)
+	assert.Nil(s.T(), err)
+
+	config := &ratelimiter.LimiterConfig{
+		IP: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+		ResponseWriter:    s.responseWriterMock,
+		ContentNegotiator: contentNegotiator,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return nil, errors.New("error")
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("Accept", "*/*")
+	recorder := httptest.NewRecorder()
+
+	s.responseWriterMock.EXPECT().WriteError(gomock.Any(), gomock.Any()).Do(func(w *http.ResponseWriter, err error) {
+		(*w).WriteHeader(500)
+	})
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 500, recorder.Result().StatusCode)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_RequestResponseWriter() {
+	config := &ratelimiter.LimiterConfig{
+		Token: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+		CustomTokens: &map[string]*ratelimiter.RateConfig{},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	block := time.Now().Add(time.Second)
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return &ratelimiter.RateLimitDecision{Allowed: false, Limit: 10, KeyType: keyType, Key: key, ResetAt: block, BlockedUntil: &block}, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing/orders", nil)
+	request.Header.Add("API_KEY", "123")
+	recorder := httptest.NewRecorder()
+
+	requestResponseWriterMock := mocks.NewMockRateLimiterRequestResponseWriter(s.controller)
+	requestResponseWriterMock.EXPECT().WriteBlockedResponse(gomock.Any(), request, gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request, decision *ratelimiter.RateLimitDecision) {
+		assert.Equal(s.T(), "/orders", r.URL.Path)
+		assert.Equal(s.T(), "TOKEN", decision.KeyType)
+		assert.Equal(s.T(), "123", decision.Key)
+		assert.Equal(s.T(), int64(10), decision.Limit)
+		assert.Equal(s.T(), block, *decision.BlockedUntil)
+		w.WriteHeader(429)
+	})
+	config.RequestResponseWriter = requestResponseWriterMock
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 429, recorder.Result().StatusCode)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_RequestResponseWriterError() {
+	config := &ratelimiter.LimiterConfig{
+		IP: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	checkErr := errors.New("error")
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		return nil, checkErr
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	recorder := httptest.NewRecorder()
+
+	requestResponseWriterMock := mocks.NewMockRateLimiterRequestResponseWriter(s.controller)
+	requestResponseWriterMock.EXPECT().WriteErrorResponse(gomock.Any(), request, gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request, err *response_writer.RateLimitError) {
+		assert.Equal(s.T(), "IP", err.KeyType)
+		assert.Equal(s.T(), "192.0.2.1", err.Key)
+		assert.Equal(s.T(), "ip", err.RuleName)
+		assert.ErrorIs(s.T(), err, checkErr)
+		w.WriteHeader(500)
+	})
+	config.RequestResponseWriter = requestResponseWriterMock
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 500, recorder.Result().StatusCode)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_TokenSources() {
+	config := &ratelimiter.LimiterConfig{
+		Token: &ratelimiter.RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+		CustomTokens: &map[string]*ratelimiter.RateConfig{},
+		TokenSources: []*ratelimiter.TokenSource{{Type: ratelimiter.TokenSourceBearer}},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	var checkedKeyType, checkedKey string
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		checkedKeyType = keyType
+		checkedKey = key
+		return nil, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "ignored")
+	request.Header.Add("Authorization", "Bearer abc")
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 200, recorder.Result().StatusCode)
+	assert.Equal(s.T(), "TOKEN", checkedKeyType)
+	assert.Equal(s.T(), "abc", checkedKey)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_AllowList() {
+	config := &ratelimiter.LimiterConfig{
+		IP:         &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		AllowedIPs: []string{"192.0.2.0/24"},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		s.T().Fatal("allowed requests must not be checked")
+		return nil, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 200, recorder.Result().StatusCode)
+	assert.Empty(s.T(), recorder.Result().Header.Get("RateLimit-Limit"))
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_DenyList() {
+	config := &ratelimiter.LimiterConfig{
+		Token:        &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		CustomTokens: &map[string]*ratelimiter.RateConfig{},
+		AllowedIPs:   []string{"192.0.2.0/24"},
+		DeniedTokens: []string{"revoked"},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		s.T().Fatal("denied requests must not be checked")
+		return nil, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "revoked")
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(s.T(), 403, response.StatusCode)
+	assert.Equal(s.T(), "access denied", string(responseBody))
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_DenyListResponseWriter() {
+	config := &ratelimiter.LimiterConfig{
+		IP:        &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		DeniedIPs: []string{"192.0.2.1"},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	recorder := httptest.NewRecorder()
+
+	requestResponseWriterMock := mocks.NewMockRateLimiterRequestResponseWriter(s.controller)
+	requestResponseWriterMock.EXPECT().WriteDeniedResponse(gomock.Any(), request).Do(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(451)
+	})
+	config.RequestResponseWriter = requestResponseWriterMock
+
+	rateLimiter(config, nextHandler, nil).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 451, recorder.Result().StatusCode)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_UnknownTokenRejected() {
+	config := &ratelimiter.LimiterConfig{
+		Token:              &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		CustomTokens:       &map[string]*ratelimiter.RateConfig{"abc": {MaxRequestsPerSecond: 20}},
+		UnknownTokenPolicy: ratelimiter.UnknownTokenPolicyReject,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		assert.Equal(s.T(), "abc", key)
+		return nil, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "random")
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(s.T(), 401, response.StatusCode)
+	assert.Equal(s.T(), "unknown API token", string(responseBody))
+
+	request = httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "abc")
+	recorder = httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 200, recorder.Result().StatusCode)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_UnknownTokenIPWithTokenPolicy() {
+	config := &ratelimiter.LimiterConfig{
+		IP:                 &ratelimiter.RateConfig{MaxRequestsPerSecond: 1, BlockTimeMilliseconds: 1000},
+		Token:              &ratelimiter.RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 1000},
+		CustomTokens:       &map[string]*ratelimiter.RateConfig{"abc": {MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 1000}},
+		StorageAdapter:     adapters.NewRateLimitMemoryStorageAdapter(),
+		ResponseWriter:     response_writer.NewRateLimiterDefaultResponseWriter(),
+		UnknownTokenPolicy: ratelimiter.UnknownTokenPolicyIP,
+		EvaluationPolicy:   ratelimiter.EvaluationPolicyToken,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	statuses := []int{}
+	for _, token := range []string{"random", "other", "abc", ""} {
+		request := httptest.NewRequest("GET", "http://testing", nil)
+		if token != "" {
+			request.Header.Add("API_KEY", token)
+		}
+		recorder := httptest.NewRecorder()
+
+		rateLimiter(config, nextHandler, ratelimiter.CheckRateLimitDecisions).ServeHTTP(recorder, request)
+		statuses = append(statuses, recorder.Result().StatusCode)
+	}
+
+	assert.Equal(s.T(), []int{200, 429, 200, 200}, statuses)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_UnknownTokenResponseWriter() {
+	config := &ratelimiter.LimiterConfig{
+		Token:              &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		CustomTokens:       &map[string]*ratelimiter.RateConfig{},
+		UnknownTokenPolicy: ratelimiter.UnknownTokenPolicyReject,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "random")
+	recorder := httptest.NewRecorder()
+
+	requestResponseWriterMock := mocks.NewMockRateLimiterRequestResponseWriter(s.controller)
+	requestResponseWriterMock.EXPECT().WriteUnauthorizedResponse(gomock.Any(), request).Do(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+	})
+	config.RequestResponseWriter = requestResponseWriterMock
+
+	rateLimiter(config, nextHandler, nil).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 403, recorder.Result().StatusCode)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_EvaluationPolicyBoth() {
+	config := &ratelimiter.LimiterConfig{
+		IP:               &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		Token:            &ratelimiter.RateConfig{MaxRequestsPerSecond: 20},
+		CustomTokens:     &map[string]*ratelimiter.RateConfig{},
+		ResponseWriter:   response_writer.NewRateLimiterDefaultResponseWriter(),
+		EvaluationPolicy: ratelimiter.EvaluationPolicyBoth,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	block := time.Now().Add(time.Second)
+	batches := 0
+	rateLimiterCheckFunction := func(ctx context.Context, checks []*ratelimiter.RateLimitCheck, config *ratelimiter.LimiterConfig) ([]*ratelimiter.RateLimitDecision, error) {
+		batches++
+		assert.Len(s.T(), checks, 2)
+		assert.Equal(s.T(), "TOKEN", checks[0].KeyType)
+		assert.Equal(s.T(), "token", checks[0].RuleName)
+		assert.Equal(s.T(), "IP", checks[1].KeyType)
+		assert.Equal(s.T(), "ip", checks[1].RuleName)
+		return []*ratelimiter.RateLimitDecision{
+			{Allowed: true, Limit: 20, Remaining: 19, KeyType: "TOKEN"},
+			{Allowed: false, Limit: 10, KeyType: "IP", ResetAt: block, BlockedUntil: &block, RetryAfter: time.Second},
+		}, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "abc")
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, rateLimiterCheckFunction).ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	assert.Equal(s.T(), 1, batches)
+	assert.Equal(s.T(), 429, response.StatusCode)
+	assert.Equal(s.T(), "10", response.Header.Get("RateLimit-Limit"))
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_EvaluationPolicyIP() {
+	config := &ratelimiter.LimiterConfig{
+		IP:               &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		Token:            &ratelimiter.RateConfig{MaxRequestsPerSecond: 20},
+		CustomTokens:     &map[string]*ratelimiter.RateConfig{},
+		EvaluationPolicy: ratelimiter.EvaluationPolicyIP,
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	var checkedKeyType string
+	rateLimiterCheckFunction := func(ctx context.Context, keyType string, key string, ruleName string, config *ratelimiter.LimiterConfig, rateConfig *ratelimiter.RateConfig) (*ratelimiter.RateLimitDecision, error) {
+		checkedKeyType = keyType
+		return nil, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Add("API_KEY", "abc")
+	recorder := httptest.NewRecorder()
+
+	rateLimiter(config, nextHandler, checkEach(rateLimiterCheckFunction)).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 200, recorder.Result().StatusCode)
+	assert.Equal(s.T(), "IP", checkedKeyType)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_KeyExtractorWithoutRateConfig() {
+	config := &ratelimiter.LimiterConfig{
+		IP:             &ratelimiter.RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 1000},
+		Token:          &ratelimiter.RateConfig{MaxRequestsPerSecond: 1, BlockTimeMilliseconds: 1000},
+		CustomTokens:   &map[string]*ratelimiter.RateConfig{},
+		StorageAdapter: adapters.NewRateLimitMemoryStorageAdapter(),
+		ResponseWriter: response_writer.NewRateLimiterDefaultResponseWriter(),
+		KeyExtractors: []KeyExtractor{
+			KeyExtractorFunc(func(r *http.Request, config *ratelimiter.LimiterConfig) (string, string, *ratelimiter.RateConfig) {
+				return "TENANT", r.Header.Get("X-Tenant"), nil
+			}),
+		},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	statuses := []int{}
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest("GET", "http://testing", nil)
+		request.Header.Add("X-Tenant", "acme")
+		recorder := httptest.NewRecorder()
+
+		rateLimiter(config, nextHandler, ratelimiter.CheckRateLimitDecisions).ServeHTTP(recorder, request)
+		statuses = append(statuses, recorder.Result().StatusCode)
+	}
+
+	assert.Equal(s.T(), []int{200, 429}, statuses)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_RouteRules() {
+	config := &ratelimiter.LimiterConfig{
+		IP:             &ratelimiter.RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 1000},
+		Token:          &ratelimiter.RateConfig{MaxRequestsPerSecond: 20, BlockTimeMilliseconds: 1000},
+		CustomTokens:   &map[string]*ratelimiter.RateConfig{},
+		StorageAdapter: adapters.NewRateLimitMemoryStorageAdapter(),
+		ResponseWriter: response_writer.NewRateLimiterDefaultResponseWriter(),
+		Routes: []*ratelimiter.RouteRule{
+			{Name: "login", Path: "/login", Methods: []string{"POST"}, Rate: &ratelimiter.RateConfig{MaxRequestsPerSecond: 1, BlockTimeMilliseconds: 1000}},
+		},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	handler := rateLimiter(config, nextHandler, ratelimiter.CheckRateLimitDecisions)
+	serve := func(method string, target string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		return recorder.Result().StatusCode
+	}
+
+	assert.Equal(s.T(), 200, serve("POST", "http://testing/login"))
+	assert.Equal(s.T(), 429, serve("POST", "http://testing/login"))
+	assert.Equal(s.T(), 200, serve("GET", "http://testing/login"))
+	assert.Equal(s.T(), 200, serve("GET", "http://testing/users"))
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware_MatchedRules() {
+	config := &ratelimiter.LimiterConfig{
+		IP:           &ratelimiter.RateConfig{MaxRequestsPerSecond: 10},
+		Token:        &ratelimiter.RateConfig{MaxRequestsPerSecond: 20},
+		CustomTokens: &map[string]*ratelimiter.RateConfig{},
+		RuleMode:     ratelimiter.RuleModeAllMatch,
+		Rules: []*ratelimiter.Rule{
+			{Name: "mobile", Match: ratelimiter.RuleMatch{Path: "/v2/*", UserAgent: "*Android*"}, Rate: &ratelimiter.RateConfig{MaxRequestsPerSecond: 50}},
+			{Name: "orders", Match: ratelimiter.RuleMatch{Path: "/v2/orders"}, Key: "{{.IP}}", Rate: &ratelimiter.RateConfig{MaxRequestsPerSecond: 5}},
+		},
+	}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	block := time.Now().Add(time.Second)
+	rateLimiterCheckFunction := func(ctx context.Context, checks []*ratelimiter.RateLimitCheck, config *ratelimiter.LimiterConfig) ([]*ratelimiter.RateLimitDecision, error) {
+		assert.Len(s.T(), checks, 2)
+		assert.Equal(s.T(), "rules.mobile", checks[0].RuleName)
+		assert.Equal(s.T(), "rules.orders", checks[1].RuleName)
+		return []*ratelimiter.RateLimitDecision{
+			{Allowed: true, Limit: 50, Remaining: 49, RuleName: checks[0].RuleName},
+			{Allowed: false, Limit: 5, RuleName: checks[1].RuleName, ResetAt: block, BlockedUntil: &block},
+		}, nil
+	}
+
+	request := httptest.NewRequest("GET", "http://testing/v2/orders", nil)
+	request.Header.Set("User-Agent", "okhttp/4.9 (Linux; Android 14)")
+	recorder := httptest.NewRecorder()
+
+	requestResponseWriterMock := mocks.NewMockRateLimiterRequestResponseWriter(s.controller)
+	requestResponseWriterMock.EXPECT().WriteBlockedResponse(gomock.Any(), request, gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request, decision *ratelimiter.RateLimitDecision) {
+		assert.Equal(s.T(), "rules.orders", decision.RuleName)
+		assert.Equal(s.T(), []string{"mobile", "orders"}, decision.MatchedRules)
+		w.WriteHeader(429)
+	})
+	config.RequestResponseWriter = requestResponseWriterMock
+
+	rateLimiter(config, nextHandler, rateLimiterCheckFunction).ServeHTTP(recorder, request)
+
+	assert.Equal(s.T(), 429, recorder.Result().StatusCode)
+}
@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	adapters "github.com/danielzinhors/rate-limiter/ratelimiter/adapters"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -80,3 +81,310 @@ func (mr *MockRateLimitStorageAdapterMockRecorder) IncrementAccesses(ctx, keyTyp
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementAccesses", reflect.TypeOf((*MockRateLimitStorageAdapter)(nil).IncrementAccesses), ctx, keyType, key, maxAccesses)
 }
+
+// MockRateLimitWindowStorageAdapter is a mock of RateLimitWindowStorageAdapter interface.
+type MockRateLimitWindowStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitWindowStorageAdapterMockRecorder
+}
+
+// MockRateLimitWindowStorageAdapterMockRecorder is the mock recorder for MockRateLimitWindowStorageAdapter.
+type MockRateLimitWindowStorageAdapterMockRecorder struct {
+	mock *MockRateLimitWindowStorageAdapter
+}
+
+// NewMockRateLimitWindowStorageAdapter creates a new mock instance.
+func NewMockRateLimitWindowStorageAdapter(ctrl *gomock.Controller) *MockRateLimitWindowStorageAdapter {
+	mock := &MockRateLimitWindowStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitWindowStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitWindowStorageAdapter) EXPECT() *MockRateLimitWindowStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// IncrementAccessesInWindow mocks base method.
+func (m *MockRateLimitWindowStorageAdapter) IncrementAccessesInWindow(ctx context.Context, keyType, key string, maxAccesses, windowMilliseconds int64) (bool, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementAccessesInWindow", ctx, keyType, key, maxAccesses, windowMilliseconds)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IncrementAccessesInWindow indicates an expected call of IncrementAccessesInWindow.
+func (mr *MockRateLimitWindowStorageAdapterMockRecorder) IncrementAccessesInWindow(ctx, keyType, key, maxAccesses, windowMilliseconds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementAccessesInWindow", reflect.TypeOf((*MockRateLimitWindowStorageAdapter)(nil).IncrementAccessesInWindow), ctx, keyType, key, maxAccesses, windowMilliseconds)
+}
+
+// MockRateLimitMultiWindowStorageAdapter is a mock of RateLimitMultiWindowStorageAdapter interface.
+type MockRateLimitMultiWindowStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitMultiWindowStorageAdapterMockRecorder
+}
+
+// MockRateLimitMultiWindowStorageAdapterMockRecorder is the mock recorder for MockRateLimitMultiWindowStorageAdapter.
+type MockRateLimitMultiWindowStorageAdapterMockRecorder struct {
+	mock *MockRateLimitMultiWindowStorageAdapter
+}
+
+// NewMockRateLimitMultiWindowStorageAdapter creates a new mock instance.
+func NewMockRateLimitMultiWindowStorageAdapter(ctrl *gomock.Controller) *MockRateLimitMultiWindowStorageAdapter {
+	mock := &MockRateLimitMultiWindowStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitMultiWindowStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitMultiWindowStorageAdapter) EXPECT() *MockRateLimitMultiWindowStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// IncrementAccessesInWindows mocks base method.
+func (m *MockRateLimitMultiWindowStorageAdapter) IncrementAccessesInWindows(ctx context.Context, keyType, key string, limits []*adapters.WindowLimit) (*adapters.WindowsResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementAccessesInWindows", ctx, keyType, key, limits)
+	ret0, _ := ret[0].(*adapters.WindowsResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementAccessesInWindows indicates an expected call of IncrementAccessesInWindows.
+func (mr *MockRateLimitMultiWindowStorageAdapterMockRecorder) IncrementAccessesInWindows(ctx, keyType, key, limits interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementAccessesInWindows", reflect.TypeOf((*MockRateLimitMultiWindowStorageAdapter)(nil).IncrementAccessesInWindows), ctx, keyType, key, limits)
+}
+
+// MockRateLimitFixedWindowStorageAdapter is a mock of RateLimitFixedWindowStorageAdapter interface.
+type MockRateLimitFixedWindowStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitFixedWindowStorageAdapterMockRecorder
+}
+
+// MockRateLimitFixedWindowStorageAdapterMockRecorder is the mock recorder for MockRateLimitFixedWindowStorageAdapter.
+type MockRateLimitFixedWindowStorageAdapterMockRecorder struct {
+	mock *MockRateLimitFixedWindowStorageAdapter
+}
+
+// NewMockRateLimitFixedWindowStorageAdapter creates a new mock instance.
+func NewMockRateLimitFixedWindowStorageAdapter(ctrl *gomock.Controller) *MockRateLimitFixedWindowStorageAdapter {
+	mock := &MockRateLimitFixedWindowStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitFixedWindowStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitFixedWindowStorageAdapter) EXPECT() *MockRateLimitFixedWindowStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// IncrementFixedWindows mocks base method.
+func (m *MockRateLimitFixedWindowStorageAdapter) IncrementFixedWindows(ctx context.Context, keyType, key string, limits []*adapters.WindowLimit) (*adapters.WindowsResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementFixedWindows", ctx, keyType, key, limits)
+	ret0, _ := ret[0].(*adapters.WindowsResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementFixedWindows indicates an expected call of IncrementFixedWindows.
+func (mr *MockRateLimitFixedWindowStorageAdapterMockRecorder) IncrementFixedWindows(ctx, keyType, key, limits interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementFixedWindows", reflect.TypeOf((*MockRateLimitFixedWindowStorageAdapter)(nil).IncrementFixedWindows), ctx, keyType, key, limits)
+}
+
+// MockRateLimitSlidingWindowCounterStorageAdapter is a mock of RateLimitSlidingWindowCounterStorageAdapter interface.
+type MockRateLimitSlidingWindowCounterStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitSlidingWindowCounterStorageAdapterMockRecorder
+}
+
+// MockRateLimitSlidingWindowCounterStorageAdapterMockRecorder is the mock recorder for MockRateLimitSlidingWindowCounterStorageAdapter.
+type MockRateLimitSlidingWindowCounterStorageAdapterMockRecorder struct {
+	mock *MockRateLimitSlidingWindowCounterStorageAdapter
+}
+
+// NewMockRateLimitSlidingWindowCounterStorageAdapter creates a new mock instance.
+func NewMockRateLimitSlidingWindowCounterStorageAdapter(ctrl *gomock.Controller) *MockRateLimitSlidingWindowCounterStorageAdapter {
+	mock := &MockRateLimitSlidingWindowCounterStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitSlidingWindowCounterStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitSlidingWindowCounterStorageAdapter) EXPECT() *MockRateLimitSlidingWindowCounterStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// IncrementSlidingWindowCounters mocks base method.
+func (m *MockRateLimitSlidingWindowCounterStorageAdapter) IncrementSlidingWindowCounters(ctx context.Context, keyType, key string, limits []*adapters.WindowLimit) (*adapters.WindowsResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementSlidingWindowCounters", ctx, keyType, key, limits)
+	ret0, _ := ret[0].(*adapters.WindowsResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementSlidingWindowCounters indicates an expected call of IncrementSlidingWindowCounters.
+func (mr *MockRateLimitSlidingWindowCounterStorageAdapterMockRecorder) IncrementSlidingWindowCounters(ctx, keyType, key, limits interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementSlidingWindowCounters", reflect.TypeOf((*MockRateLimitSlidingWindowCounterStorageAdapter)(nil).IncrementSlidingWindowCounters), ctx, keyType, key, limits)
+}
+
+// MockRateLimitTokenBucketStorageAdapter is a mock of RateLimitTokenBucketStorageAdapter interface.
+type MockRateLimitTokenBucketStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitTokenBucketStorageAdapterMockRecorder
+}
+
+// MockRateLimitTokenBucketStorageAdapterMockRecorder is the mock recorder for MockRateLimitTokenBucketStorageAdapter.
+type MockRateLimitTokenBucketStorageAdapterMockRecorder struct {
+	mock *MockRateLimitTokenBucketStorageAdapter
+}
+
+// NewMockRateLimitTokenBucketStorageAdapter creates a new mock instance.
+func NewMockRateLimitTokenBucketStorageAdapter(ctrl *gomock.Controller) *MockRateLimitTokenBucketStorageAdapter {
+	mock := &MockRateLimitTokenBucketStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitTokenBucketStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitTokenBucketStorageAdapter) EXPECT() *MockRateLimitTokenBucketStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// TakeToken mocks base method.
+func (m *MockRateLimitTokenBucketStorageAdapter) TakeToken(ctx context.Context, keyType, key string, capacity, refillRatePerSecond int64) (bool, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeToken", ctx, keyType, key, capacity, refillRatePerSecond)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TakeToken indicates an expected call of TakeToken.
+func (mr *MockRateLimitTokenBucketStorageAdapterMockRecorder) TakeToken(ctx, keyType, key, capacity, refillRatePerSecond interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeToken", reflect.TypeOf((*MockRateLimitTokenBucketStorageAdapter)(nil).TakeToken), ctx, keyType, key, capacity, refillRatePerSecond)
+}
+
+// MockRateLimitGCRAStorageAdapter is a mock of RateLimitGCRAStorageAdapter interface.
+type MockRateLimitGCRAStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitGCRAStorageAdapterMockRecorder
+}
+
+// MockRateLimitGCRAStorageAdapterMockRecorder is the mock recorder for MockRateLimitGCRAStorageAdapter.
+type MockRateLimitGCRAStorageAdapterMockRecorder struct {
+	mock *MockRateLimitGCRAStorageAdapter
+}
+
+// NewMockRateLimitGCRAStorageAdapter creates a new mock instance.
+func NewMockRateLimitGCRAStorageAdapter(ctrl *gomock.Controller) *MockRateLimitGCRAStorageAdapter {
+	mock := &MockRateLimitGCRAStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitGCRAStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitGCRAStorageAdapter) EXPECT() *MockRateLimitGCRAStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// CheckCellRate mocks base method.
+func (m *MockRateLimitGCRAStorageAdapter) CheckCellRate(ctx context.Context, keyType, key string, maxRequests, windowMilliseconds, burst int64) (bool, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckCellRate", ctx, keyType, key, maxRequests, windowMilliseconds, burst)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CheckCellRate indicates an expected call of CheckCellRate.
+func (mr *MockRateLimitGCRAStorageAdapterMockRecorder) CheckCellRate(ctx, keyType, key, maxRequests, windowMilliseconds, burst interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckCellRate", reflect.TypeOf((*MockRateLimitGCRAStorageAdapter)(nil).CheckCellRate), ctx, keyType, key, maxRequests, windowMilliseconds, burst)
+}
+
+// MockRateLimitCheckStorageAdapter is a mock of RateLimitCheckStorageAdapter interface.
+type MockRateLimitCheckStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitCheckStorageAdapterMockRecorder
+}
+
+// MockRateLimitCheckStorageAdapterMockRecorder is the mock recorder for MockRateLimitCheckStorageAdapter.
+type MockRateLimitCheckStorageAdapterMockRecorder struct {
+	mock *MockRateLimitCheckStorageAdapter
+}
+
+// NewMockRateLimitCheckStorageAdapter creates a new mock instance.
+func NewMockRateLimitCheckStorageAdapter(ctrl *gomock.Controller) *MockRateLimitCheckStorageAdapter {
+	mock := &MockRateLimitCheckStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitCheckStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitCheckStorageAdapter) EXPECT() *MockRateLimitCheckStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockRateLimitCheckStorageAdapter) Check(ctx context.Context, keyType, key string, request *adapters.CheckRequest) (*adapters.CheckResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, keyType, key, request)
+	ret0, _ := ret[0].(*adapters.CheckResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockRateLimitCheckStorageAdapterMockRecorder) Check(ctx, keyType, key, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockRateLimitCheckStorageAdapter)(nil).Check), ctx, keyType, key, request)
+}
+
+// MockRateLimitBatchCheckStorageAdapter is a mock of RateLimitBatchCheckStorageAdapter interface.
+type MockRateLimitBatchCheckStorageAdapter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitBatchCheckStorageAdapterMockRecorder
+}
+
+// MockRateLimitBatchCheckStorageAdapterMockRecorder is the mock recorder for MockRateLimitBatchCheckStorageAdapter.
+type MockRateLimitBatchCheckStorageAdapterMockRecorder struct {
+	mock *MockRateLimitBatchCheckStorageAdapter
+}
+
+// NewMockRateLimitBatchCheckStorageAdapter creates a new mock instance.
+func NewMockRateLimitBatchCheckStorageAdapter(ctrl *gomock.Controller) *MockRateLimitBatchCheckStorageAdapter {
+	mock := &MockRateLimitBatchCheckStorageAdapter{ctrl: ctrl}
+	mock.recorder = &MockRateLimitBatchCheckStorageAdapterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitBatchCheckStorageAdapter) EXPECT() *MockRateLimitBatchCheckStorageAdapterMockRecorder {
+	return m.recorder
+}
+
+// CheckBatch mocks base method.
+func (m *MockRateLimitBatchCheckStorageAdapter) CheckBatch(ctx context.Context, requests []*adapters.BatchCheckRequest) ([]*adapters.CheckResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBatch", ctx, requests)
+	ret0, _ := ret[0].([]*adapters.CheckResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckBatch indicates an expected call of CheckBatch.
+func (mr *MockRateLimitBatchCheckStorageAdapterMockRecorder) CheckBatch(ctx, requests interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBatch", reflect.TypeOf((*MockRateLimitBatchCheckStorageAdapter)(nil).CheckBatch), ctx, requests)
+}
@@ -3,39 +3,214 @@ package ratelimiter
 import (
 	"context"
 	"time"
+
+	"github.com/danielzinhors/rate-limiter/ratelimiter/adapters"
+	"github.com/danielzinhors/rate-limiter/ratelimiter/response_writer"
 )
 
+type RateLimitDecision = response_writer.RateLimitDecision
+
 func CheckRateLimit(ctx context.Context, keyType string, key string, limitConf *LimiterConfig, rateConfig *RateConfig) (*time.Time, error) {
-	if key == "" {
-		return nil, nil
+	block, _, err := CheckRateLimitWithWindow(ctx, keyType, key, limitConf, rateConfig)
+	return block, err
+}
+
+func CheckRateLimitWithWindow(ctx context.Context, keyType string, key string, limitConf *LimiterConfig, rateConfig *RateConfig) (*time.Time, *WindowLimit, error) {
+	decision, exceededWindow, err := checkRateLimit(ctx, keyType, key, "", limitConf, rateConfig)
+	if err != nil || decision == nil || decision.Allowed {
+		return nil, nil, err
+	}
+	return &decision.ResetAt, exceededWindow, nil
+}
+
+func CheckRateLimitDecision(ctx context.Context, keyType string, key string, ruleName string, limitConf *LimiterConfig, rateConfig *RateConfig) (*RateLimitDecision, error) {
+	decision, _, err := checkRateLimit(ctx, keyType, key, ruleName, limitConf, rateConfig)
+	return decision, err
+}
+
+// CheckRateLimitDecisions checks every limit that applies to a request with a single storage call, in the order of checks.
+// Checks with an empty key get a nil decision. Each limit counts the request on its own, so a request rejected by one of
+// them still counts against the others.
+func CheckRateLimitDecisions(ctx context.Context, checks []*RateLimitCheck, limitConf *LimiterConfig) ([]*RateLimitDecision, error) {
+	decisions := make([]*RateLimitDecision, len(checks))
+
+	indexes := []int{}
+	requests := []*adapters.BatchCheckRequest{}
+	for i, check := range checks {
+		if check.Key == "" {
+			continue
+		}
+		if err := check.RateConfig.Validate(); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, i)
+		requests = append(requests, &adapters.BatchCheckRequest{
+			KeyType: check.getStorageKeyType(),
+			Key:     limitConf.GetStorageKey(check.KeyType, check.Key),
+			Request: newCheckRequest(check.RateConfig, check.RateConfig.GetLimits()),
+		})
 	}
 
-	block, err := limitConf.StorageAdapter.GetBlock(ctx, keyType, key)
+	if len(requests) == 0 {
+		return decisions, nil
+	}
+
+	results, err := checkAccessBatch(ctx, limitConf.StorageAdapter, requests)
 	if err != nil {
 		return nil, err
 	}
 
-	if block == nil {
-		success, count, err := limitConf.StorageAdapter.IncrementAccesses(ctx, keyType, key, rateConfig.MaxRequestsPerSecond)
-		if err != nil {
-			return nil, err
-		}
+	for i, index := range indexes {
+		check := checks[index]
+		decisions[index], _ = newCheckDecision(limitConf, check.KeyType, check.Key, check.RuleName, check.RateConfig, requests[i].Request, results[i])
+	}
+
+	return decisions, nil
+}
 
-		if success {
-			PrintfD(limitConf, "%d of %d (%dms if blocked)", keyType, key, count, rateConfig.MaxRequestsPerSecond, rateConfig.BlockTimeMilliseconds)
+func checkRateLimit(ctx context.Context, keyType string, key string, ruleName string, limitConf *LimiterConfig, rateConfig *RateConfig) (*RateLimitDecision, *WindowLimit, error) {
+	if key == "" {
+		return nil, nil, nil
+	}
+	if err := rateConfig.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	request := newCheckRequest(rateConfig, rateConfig.GetLimits())
+
+	result, err := checkAccess(ctx, keyType, limitConf.GetStorageKey(keyType, key), limitConf.StorageAdapter, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decision, exceededWindow := newCheckDecision(limitConf, keyType, key, ruleName, rateConfig, request, result)
+	return decision, exceededWindow, nil
+}
+
+func newCheckDecision(limitConf *LimiterConfig, keyType string, key string, ruleName string, rateConfig *RateConfig, request *adapters.CheckRequest, result *adapters.CheckResult) (*RateLimitDecision, *WindowLimit) {
+	decision, exceededWindow := newRateLimitDecision(request, rateConfig.GetLimits(), result, time.Now())
+	decision.KeyType = keyType
+	decision.Key = limitConf.GetRedactedKey(keyType, key)
+	decision.RuleName = ruleName
+
+	if !result.AlreadyBlocked {
+		if result.Success {
+			PrintfD(limitConf, "%d of %d (%dms if blocked)", keyType, key, decision.Limit-decision.Remaining, decision.Limit, rateConfig.BlockTimeMilliseconds)
 		} else {
+			if exceededWindow != nil {
+				PrintfD(limitConf, "limit of %d per %dms exceeded", keyType, key, exceededWindow.MaxRequests, exceededWindow.WindowMilliseconds)
+			}
 			PrintfD(limitConf, "adding a block of %dms", keyType, key, rateConfig.BlockTimeMilliseconds)
-			block, err = limitConf.StorageAdapter.AddBlock(ctx, keyType, key, rateConfig.BlockTimeMilliseconds)
-			if err != nil {
-				return nil, err
+		}
+	}
+
+	if !decision.Allowed {
+		PrintfD(limitConf, "block time %.2f seconds", keyType, key, GetBlockTime(&decision.ResetAt))
+	}
+
+	return decision, exceededWindow
+}
+
+func checkAccess(ctx context.Context, keyType string, key string, adapter adapters.RateLimitStorageAdapter, request *adapters.CheckRequest) (*adapters.CheckResult, error) {
+	if checkAdapter, ok := adapter.(adapters.RateLimitCheckStorageAdapter); ok {
+		return checkAdapter.Check(ctx, keyType, key, request)
+	}
+	return adapters.CheckWithStorageAdapter(ctx, adapter, keyType, key, request)
+}
+
+func checkAccessBatch(ctx context.Context, adapter adapters.RateLimitStorageAdapter, requests []*adapters.BatchCheckRequest) ([]*adapters.CheckResult, error) {
+	if batchAdapter, ok := adapter.(adapters.RateLimitBatchCheckStorageAdapter); ok {
+		return batchAdapter.CheckBatch(ctx, requests)
+	}
+	return adapters.CheckBatchWithStorageAdapter(ctx, adapter, requests)
+}
+
+func newCheckRequest(rateConfig *RateConfig, rateLimits []*WindowLimit) *adapters.CheckRequest {
+	limits := []*adapters.WindowLimit{}
+	for _, limit := range rateLimits {
+		limits = append(limits, &adapters.WindowLimit{MaxAccesses: limit.MaxRequests, WindowMilliseconds: limit.WindowMilliseconds})
+	}
+
+	return &adapters.CheckRequest{
+		Algorithm:             rateConfig.GetAlgorithm(),
+		Limits:                limits,
+		BucketCapacity:        rateConfig.GetBucketCapacity(),
+		RefillRatePerSecond:   rateConfig.GetRefillRatePerSecond(),
+		BlockTimeMilliseconds: rateConfig.BlockTimeMilliseconds,
+	}
+}
+
+func newRateLimitDecision(request *adapters.CheckRequest, rateLimits []*WindowLimit, result *adapters.CheckResult, now time.Time) (*RateLimitDecision, *WindowLimit) {
+	isBucket := request.Algorithm == AlgorithmTokenBucket || request.Algorithm == AlgorithmGCRA
+
+	if result.AlreadyBlocked || !result.Success {
+		decision := &RateLimitDecision{Allowed: false, Limit: rateLimits[0].MaxRequests, WindowMilliseconds: rateLimits[0].WindowMilliseconds, ResetAt: now}
+		if result.BlockedUntil != nil {
+			decision.ResetAt = *result.BlockedUntil
+		}
+		decision.BlockedUntil = &decision.ResetAt
+		if decision.ResetAt.After(now) {
+			decision.RetryAfter = decision.ResetAt.Sub(now)
+		}
+
+		var exceededWindow *WindowLimit
+		if !result.AlreadyBlocked && request.Algorithm != AlgorithmTokenBucket && result.ExceededIndex >= 0 && result.ExceededIndex < len(rateLimits) {
+			exceededWindow = rateLimits[result.ExceededIndex]
+			decision.Limit = exceededWindow.MaxRequests
+			decision.WindowMilliseconds = exceededWindow.WindowMilliseconds
+		}
+		if isBucket {
+			decision.Limit = request.BucketCapacity
+		}
+		if request.Algorithm == AlgorithmTokenBucket {
+			decision.WindowMilliseconds = getRefillWindowMilliseconds(request)
+		}
+		return decision, exceededWindow
+	}
+
+	if isBucket {
+		decision := &RateLimitDecision{Allowed: true, Limit: request.BucketCapacity, Remaining: result.Remaining, ResetAt: now}
+		used := request.BucketCapacity - result.Remaining
+		if request.Algorithm == AlgorithmTokenBucket {
+			decision.WindowMilliseconds = getRefillWindowMilliseconds(request)
+			if request.RefillRatePerSecond > 0 {
+				decision.ResetAt = now.Add(time.Duration(used) * time.Second / time.Duration(request.RefillRatePerSecond))
+			}
+		} else {
+			decision.WindowMilliseconds = rateLimits[0].WindowMilliseconds
+			if rateLimits[0].MaxRequests > 0 {
+				emissionInterval := time.Duration(rateLimits[0].WindowMilliseconds) * time.Millisecond / time.Duration(rateLimits[0].MaxRequests)
+				decision.ResetAt = now.Add(time.Duration(used) * emissionInterval)
 			}
 		}
+		return decision, nil
 	}
 
-	if block != nil {
-		PrintfD(limitConf, "block time %.2f seconds", keyType, key, GetBlockTime(block))
-		return block, nil
+	tightest := 0
+	for i, limit := range rateLimits {
+		if limit.MaxRequests-result.Counts[i] < rateLimits[tightest].MaxRequests-result.Counts[tightest] {
+			tightest = i
+		}
+	}
+	limit := rateLimits[tightest]
+
+	decision := &RateLimitDecision{Allowed: true, Limit: limit.MaxRequests, WindowMilliseconds: limit.WindowMilliseconds}
+	if remaining := limit.MaxRequests - result.Counts[tightest]; remaining > 0 {
+		decision.Remaining = remaining
+	}
+
+	if request.Algorithm == AlgorithmFixedWindow {
+		decision.ResetAt = time.UnixMilli((now.UnixMilli()/limit.WindowMilliseconds + 1) * limit.WindowMilliseconds)
+	} else {
+		decision.ResetAt = now.Add(time.Duration(limit.WindowMilliseconds) * time.Millisecond)
 	}
 
-	return nil, nil
+	return decision, nil
+}
+
+func getRefillWindowMilliseconds(request *adapters.CheckRequest) int64 {
+	if request.RefillRatePerSecond <= 0 {
+		return 0
+	}
+	return request.BucketCapacity * 1000 / request.RefillRatePerSecond
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/danielzinhors/rate-limiter/ratelimiter/adapters"
 	"github.com/danielzinhors/rate-limiter/ratelimiter/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -14,9 +15,37 @@ import (
 
 type RateLimiterTestSuite struct {
 	suite.Suite
-	controller         *gomock.Controller
-	context            context.Context
-	storageAdapterMock *mocks.MockRateLimitStorageAdapter
+	controller            *gomock.Controller
+	context               context.Context
+	storageAdapterMock    *mocks.MockRateLimitStorageAdapter
+	checkAdapterMock      *mocks.MockRateLimitCheckStorageAdapter
+	batchCheckAdapterMock *mocks.MockRateLimitBatchCheckStorageAdapter
+	windowAdapterMock     *mocks.MockRateLimitWindowStorageAdapter
+	windowsAdapterMock    *mocks.MockRateLimitMultiWindowStorageAdapter
+	fixedAdapterMock      *mocks.MockRateLimitFixedWindowStorageAdapter
+	counterAdapterMock    *mocks.MockRateLimitSlidingWindowCounterStorageAdapter
+	tokenBucketMock       *mocks.MockRateLimitTokenBucketStorageAdapter
+	gcraAdapterMock       *mocks.MockRateLimitGCRAStorageAdapter
+}
+
+type checkStorageAdapterMock struct {
+	*mocks.MockRateLimitStorageAdapter
+	*mocks.MockRateLimitCheckStorageAdapter
+}
+
+type algorithmsStorageAdapterMock struct {
+	*mocks.MockRateLimitStorageAdapter
+	*mocks.MockRateLimitWindowStorageAdapter
+	*mocks.MockRateLimitMultiWindowStorageAdapter
+	*mocks.MockRateLimitFixedWindowStorageAdapter
+	*mocks.MockRateLimitSlidingWindowCounterStorageAdapter
+	*mocks.MockRateLimitTokenBucketStorageAdapter
+	*mocks.MockRateLimitGCRAStorageAdapter
+}
+
+type batchCheckStorageAdapterMock struct {
+	*mocks.MockRateLimitStorageAdapter
+	*mocks.MockRateLimitBatchCheckStorageAdapter
 }
 
 func TestRateLimiterTestSuite(t *testing.T) {
@@ -27,6 +56,26 @@ func (s *RateLimiterTestSuite) SetupTest() {
 	s.controller = gomock.NewController(s.T())
 	s.context = context.Background()
 	s.storageAdapterMock = mocks.NewMockRateLimitStorageAdapter(s.controller)
+	s.checkAdapterMock = mocks.NewMockRateLimitCheckStorageAdapter(s.controller)
+	s.batchCheckAdapterMock = mocks.NewMockRateLimitBatchCheckStorageAdapter(s.controller)
+	s.windowAdapterMock = mocks.NewMockRateLimitWindowStorageAdapter(s.controller)
+	s.windowsAdapterMock = mocks.NewMockRateLimitMultiWindowStorageAdapter(s.controller)
+	s.fixedAdapterMock = mocks.NewMockRateLimitFixedWindowStorageAdapter(s.controller)
+	s.counterAdapterMock = mocks.NewMockRateLimitSlidingWindowCounterStorageAdapter(s.controller)
+	s.tokenBucketMock = mocks.NewMockRateLimitTokenBucketStorageAdapter(s.controller)
+	s.gcraAdapterMock = mocks.NewMockRateLimitGCRAStorageAdapter(s.controller)
+}
+
+func (s *RateLimiterTestSuite) newAlgorithmsStorageAdapterMock() *algorithmsStorageAdapterMock {
+	return &algorithmsStorageAdapterMock{
+		s.storageAdapterMock,
+		s.windowAdapterMock,
+		s.windowsAdapterMock,
+		s.fixedAdapterMock,
+		s.counterAdapterMock,
+		s.tokenBucketMock,
+		s.gcraAdapterMock,
+	}
 }
 
 func (s *RateLimiterTestSuite) TestCheckRateLimit_AccessAllowed() {
@@ -81,6 +130,240 @@ func (s *RateLimiterTestSuite) TestCheckRateLimit_AccessDenied() {
 	assert.Equal(s.T(), block, *returnedBlock)
 }
 
+func (s *RateLimiterTestSuite) TestCheckRateLimit_CustomWindow() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			MaxRequestsPerSecond:  1000,
+			BlockTimeMilliseconds: 100,
+			WindowMilliseconds:    60000,
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.windowAdapterMock.EXPECT().
+		IncrementAccessesInWindow(context, keyType, key, int64(1000), int64(60000)).Return(true, int64(1), nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_HashedToken() {
+	context := s.context
+	keyType := "TOKEN"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+		TokenHashSecret: "secret",
+	}
+	hashedKey := "9946dad4e00e913fc8be8e5d3f7e110a4a9e832f83fb09c345285d78638d8a0e"
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, hashedKey).Return(nil, nil).Times(1)
+
+	s.storageAdapterMock.EXPECT().
+		IncrementAccesses(context, keyType, hashedKey, int64(10)).Return(true, int64(1), nil).Times(1)
+
+	config.StorageAdapter = s.storageAdapterMock
+
+	decision, err := CheckRateLimitDecision(context, keyType, "abc", "token", config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), decision.Allowed)
+	assert.Equal(s.T(), hashedKey, decision.Key)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_MultipleWindowsAllowed() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			BlockTimeMilliseconds: 100,
+			Limits: []*WindowLimit{
+				{MaxRequests: 20, WindowMilliseconds: 1000},
+				{MaxRequests: 500, WindowMilliseconds: 60000},
+			},
+		},
+	}
+	expectedLimits := []*adapters.WindowLimit{
+		{MaxAccesses: 20, WindowMilliseconds: 1000},
+		{MaxAccesses: 500, WindowMilliseconds: 60000},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.windowsAdapterMock.EXPECT().
+		IncrementAccessesInWindows(context, keyType, key, expectedLimits).
+		Return(&adapters.WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1, 1}}, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, returnedWindow, err := CheckRateLimitWithWindow(context, keyType, key, config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+	assert.Nil(s.T(), returnedWindow)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_MultipleWindowsDenied() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			BlockTimeMilliseconds: 100,
+			Limits: []*WindowLimit{
+				{MaxRequests: 20, WindowMilliseconds: 1000},
+				{MaxRequests: 500, WindowMilliseconds: 60000},
+				{MaxRequests: 100000, WindowMilliseconds: 86400000},
+			},
+		},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.windowsAdapterMock.EXPECT().
+		IncrementAccessesInWindows(context, keyType, key, gomock.Any()).
+		Return(&adapters.WindowsResult{Success: false, ExceededIndex: 1, Counts: []int64{3, 500, 500}}, nil).Times(1)
+
+	s.storageAdapterMock.EXPECT().
+		AddBlock(context, keyType, key, config.Token.BlockTimeMilliseconds).Return(&block, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, returnedWindow, err := CheckRateLimitWithWindow(context, keyType, key, config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), block, *returnedBlock)
+	assert.Equal(s.T(), config.Token.Limits[1], returnedWindow)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_MultipleWindowsError() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			BlockTimeMilliseconds: 100,
+			Limits: []*WindowLimit{
+				{MaxRequests: 20, WindowMilliseconds: 1000},
+				{MaxRequests: 500, WindowMilliseconds: 60000},
+			},
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.windowsAdapterMock.EXPECT().
+		IncrementAccessesInWindows(context, keyType, key, gomock.Any()).Return(nil, errors.New("error")).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.Token)
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_FixedWindowAllowed() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmFixedWindow,
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.fixedAdapterMock.EXPECT().
+		IncrementFixedWindows(context, keyType, key, []*adapters.WindowLimit{{MaxAccesses: 10, WindowMilliseconds: 1000}}).
+		Return(&adapters.WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{1}}, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_FixedWindowDenied() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmFixedWindow,
+		},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.fixedAdapterMock.EXPECT().
+		IncrementFixedWindows(context, keyType, key, gomock.Any()).
+		Return(&adapters.WindowsResult{Success: false, ExceededIndex: 0, Counts: []int64{11}}, nil).Times(1)
+
+	s.storageAdapterMock.EXPECT().
+		AddBlock(context, keyType, key, config.IP.BlockTimeMilliseconds).Return(&block, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, returnedWindow, err := CheckRateLimitWithWindow(context, keyType, key, config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), block, *returnedBlock)
+	assert.Equal(s.T(), &WindowLimit{MaxRequests: 10, WindowMilliseconds: 1000}, returnedWindow)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_SlidingWindowCounterAllowed() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmSlidingWindowCounter,
+			Limits: []*WindowLimit{
+				{MaxRequests: 20, WindowMilliseconds: 1000},
+				{MaxRequests: 500, WindowMilliseconds: 60000},
+			},
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.counterAdapterMock.EXPECT().
+		IncrementSlidingWindowCounters(context, keyType, key, []*adapters.WindowLimit{
+			{MaxAccesses: 20, WindowMilliseconds: 1000},
+			{MaxAccesses: 500, WindowMilliseconds: 60000},
+		}).
+		Return(&adapters.WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{5, 300}}, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
 func (s *RateLimiterTestSuite) TestCheckRateLimit_AlreadyBlocked() {
 	context := s.context
 	keyType := "IP"
@@ -192,3 +475,551 @@ func (s *RateLimiterTestSuite) TestCheckRateLimit_AddBlockError() {
 	assert.NotNil(s.T(), err)
 	assert.Nil(s.T(), returnedBlock)
 }
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_TokenBucketAllowed() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmTokenBucket,
+			BucketCapacity:        50,
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.tokenBucketMock.EXPECT().
+		TakeToken(context, keyType, key, int64(50), int64(10)).Return(true, int64(49), nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_TokenBucketDenied() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmTokenBucket,
+			BucketCapacity:        50,
+			RefillRatePerSecond:   5,
+		},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.tokenBucketMock.EXPECT().
+		TakeToken(context, keyType, key, int64(50), int64(5)).Return(false, int64(0), nil).Times(1)
+
+	s.storageAdapterMock.EXPECT().
+		AddBlock(context, keyType, key, config.Token.BlockTimeMilliseconds).Return(&block, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), block, *returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_GCRAAllowed() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmGCRA,
+			BucketCapacity:        20,
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.gcraAdapterMock.EXPECT().
+		CheckCellRate(context, keyType, key, int64(10), int64(1000), int64(20)).Return(true, int64(19), nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_GCRADenied() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmGCRA,
+		},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.gcraAdapterMock.EXPECT().
+		CheckCellRate(context, keyType, key, int64(10), int64(1000), int64(10)).Return(false, int64(0), nil).Times(1)
+
+	s.storageAdapterMock.EXPECT().
+		AddBlock(context, keyType, key, config.IP.BlockTimeMilliseconds).Return(&block, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), block, *returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_UnknownAlgorithm() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             "unknown",
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	config.StorageAdapter = s.storageAdapterMock
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_InvalidWindow() {
+	config := &LimiterConfig{
+		IP: &RateConfig{Limits: []*WindowLimit{{MaxRequests: 5}}},
+	}
+
+	config.StorageAdapter = s.storageAdapterMock
+
+	returnedBlock, err := CheckRateLimit(s.context, "IP", "127.0.0.1", config, config.IP)
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_AlgorithmNotSupported() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmTokenBucket,
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	config.StorageAdapter = s.storageAdapterMock
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.ErrorIs(s.T(), err, adapters.ErrAlgorithmNotSupported)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_WindowNotSupported() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+			WindowMilliseconds:    60000,
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	config.StorageAdapter = s.storageAdapterMock
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.ErrorIs(s.T(), err, adapters.ErrAlgorithmNotSupported)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_CheckAdapterAllowed() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+	}
+	request := &adapters.CheckRequest{
+		Algorithm:             AlgorithmSlidingLog,
+		Limits:                []*adapters.WindowLimit{{MaxAccesses: 10, WindowMilliseconds: 1000}},
+		BucketCapacity:        10,
+		RefillRatePerSecond:   10,
+		BlockTimeMilliseconds: 100,
+	}
+
+	s.checkAdapterMock.EXPECT().
+		Check(context, keyType, key, request).
+		Return(&adapters.CheckResult{Success: true, ExceededIndex: -1, Counts: []int64{1}}, nil).Times(1)
+
+	config.StorageAdapter = &checkStorageAdapterMock{s.storageAdapterMock, s.checkAdapterMock}
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_CheckAdapterDenied() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmFixedWindow,
+			Limits: []*WindowLimit{
+				{MaxRequests: 20, WindowMilliseconds: 1000},
+				{MaxRequests: 500, WindowMilliseconds: 60000},
+			},
+		},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.checkAdapterMock.EXPECT().
+		Check(context, keyType, key, gomock.Any()).
+		Return(&adapters.CheckResult{Success: false, BlockedUntil: &block, ExceededIndex: 1, Counts: []int64{3, 501}}, nil).Times(1)
+
+	config.StorageAdapter = &checkStorageAdapterMock{s.storageAdapterMock, s.checkAdapterMock}
+
+	returnedBlock, returnedWindow, err := CheckRateLimitWithWindow(context, keyType, key, config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), block, *returnedBlock)
+	assert.Equal(s.T(), config.Token.Limits[1], returnedWindow)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_CheckAdapterAlreadyBlocked() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.checkAdapterMock.EXPECT().
+		Check(context, keyType, key, gomock.Any()).
+		Return(&adapters.CheckResult{AlreadyBlocked: true, BlockedUntil: &block, ExceededIndex: -1}, nil).Times(1)
+
+	config.StorageAdapter = &checkStorageAdapterMock{s.storageAdapterMock, s.checkAdapterMock}
+
+	returnedBlock, returnedWindow, err := CheckRateLimitWithWindow(context, keyType, key, config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), block, *returnedBlock)
+	assert.Nil(s.T(), returnedWindow)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimit_CheckAdapterError() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+	}
+
+	s.checkAdapterMock.EXPECT().
+		Check(context, keyType, key, gomock.Any()).Return(nil, errors.New("error")).Times(1)
+
+	config.StorageAdapter = &checkStorageAdapterMock{s.storageAdapterMock, s.checkAdapterMock}
+
+	returnedBlock, err := CheckRateLimit(context, keyType, key, config, config.IP)
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), returnedBlock)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecision_Allowed() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmFixedWindow,
+			Limits: []*WindowLimit{
+				{MaxRequests: 10, WindowMilliseconds: 1000},
+				{MaxRequests: 100, WindowMilliseconds: 60000},
+			},
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.fixedAdapterMock.EXPECT().
+		IncrementFixedWindows(context, keyType, key, gomock.Any()).
+		Return(&adapters.WindowsResult{Success: true, ExceededIndex: -1, Counts: []int64{3, 98}}, nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	decision, err := CheckRateLimitDecision(context, keyType, key, "ip", config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), decision.Allowed)
+	assert.Equal(s.T(), int64(100), decision.Limit)
+	assert.Equal(s.T(), int64(2), decision.Remaining)
+	assert.Equal(s.T(), int64(60000), decision.WindowMilliseconds)
+	assert.Equal(s.T(), int64(0), decision.ResetAt.UnixMilli()%60000)
+	assert.Equal(s.T(), time.Duration(0), decision.RetryAfter)
+	assert.Equal(s.T(), keyType, decision.KeyType)
+	assert.Equal(s.T(), key, decision.Key)
+	assert.Equal(s.T(), "ip", decision.RuleName)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecision_Denied() {
+	context := s.context
+	keyType := "TOKEN"
+	key := "abc"
+	config := &LimiterConfig{
+		Token: &RateConfig{
+			MaxRequestsPerSecond:  10,
+			BlockTimeMilliseconds: 100,
+		},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.storageAdapterMock.EXPECT().
+		IncrementAccesses(context, keyType, key, gomock.Any()).Return(false, int64(10), nil).Times(1)
+
+	s.storageAdapterMock.EXPECT().
+		AddBlock(context, keyType, key, config.Token.BlockTimeMilliseconds).Return(&block, nil).Times(1)
+
+	config.StorageAdapter = s.storageAdapterMock
+
+	decision, err := CheckRateLimitDecision(context, keyType, key, "token", config, config.Token)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), decision.Allowed)
+	assert.Equal(s.T(), int64(10), decision.Limit)
+	assert.Equal(s.T(), int64(0), decision.Remaining)
+	assert.Equal(s.T(), block, decision.ResetAt)
+	assert.True(s.T(), decision.RetryAfter > 0 && decision.RetryAfter <= time.Millisecond*100)
+	assert.Equal(s.T(), "token", decision.RuleName)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecision_TokenBucket() {
+	context := s.context
+	keyType := "IP"
+	key := "127.0.0.1"
+	config := &LimiterConfig{
+		IP: &RateConfig{
+			BlockTimeMilliseconds: 100,
+			Algorithm:             AlgorithmTokenBucket,
+			BucketCapacity:        10,
+			RefillRatePerSecond:   2,
+		},
+	}
+
+	s.storageAdapterMock.EXPECT().
+		GetBlock(context, keyType, key).Return(nil, nil).Times(1)
+
+	s.tokenBucketMock.EXPECT().
+		TakeToken(context, keyType, key, int64(10), int64(2)).Return(true, int64(6), nil).Times(1)
+
+	config.StorageAdapter = s.newAlgorithmsStorageAdapterMock()
+
+	before := time.Now()
+	decision, err := CheckRateLimitDecision(context, keyType, key, "ip", config, config.IP)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), decision.Allowed)
+	assert.Equal(s.T(), int64(10), decision.Limit)
+	assert.Equal(s.T(), int64(6), decision.Remaining)
+	assert.WithinDuration(s.T(), before.Add(time.Second*2), decision.ResetAt, time.Millisecond*50)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecision_EmptyKey() {
+	decision, err := CheckRateLimitDecision(s.context, "IP", "", "ip", &LimiterConfig{}, &RateConfig{})
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), decision)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecisions_Batch() {
+	context := s.context
+	config := &LimiterConfig{
+		IP:              &RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 100},
+		Token:           &RateConfig{MaxRequestsPerSecond: 20, BlockTimeMilliseconds: 100},
+		TokenHashSecret: "secret",
+	}
+	checks := []*RateLimitCheck{
+		{KeyType: "TOKEN", Key: "abc", RuleName: "token", RateConfig: config.Token},
+		{KeyType: "IP", Key: "", RuleName: "ip", RateConfig: config.IP},
+		{KeyType: "IP", Key: "127.0.0.1", RuleName: "ip", RateConfig: config.IP},
+	}
+	block := time.Now().Add(time.Millisecond * 100)
+
+	s.batchCheckAdapterMock.EXPECT().
+		CheckBatch(context, gomock.Any()).
+		DoAndReturn(func(ctx interface{}, requests []*adapters.BatchCheckRequest) ([]*adapters.CheckResult, error) {
+			assert.Len(s.T(), requests, 2)
+			assert.Equal(s.T(), config.HashToken("abc"), requests[0].Key)
+			assert.Equal(s.T(), int64(20), requests[0].Request.Limits[0].MaxAccesses)
+			assert.Equal(s.T(), "127.0.0.1", requests[1].Key)
+			return []*adapters.CheckResult{
+				{Success: true, ExceededIndex: -1, Counts: []int64{1}},
+				{Success: false, BlockedUntil: &block, ExceededIndex: 0, Counts: []int64{11}},
+			}, nil
+		}).Times(1)
+
+	config.StorageAdapter = &batchCheckStorageAdapterMock{s.storageAdapterMock, s.batchCheckAdapterMock}
+
+	decisions, err := CheckRateLimitDecisions(context, checks, config)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), decisions, 3)
+	assert.True(s.T(), decisions[0].Allowed)
+	assert.Equal(s.T(), config.HashToken("abc"), decisions[0].Key)
+	assert.Nil(s.T(), decisions[1])
+	assert.False(s.T(), decisions[2].Allowed)
+	assert.Equal(s.T(), block, decisions[2].ResetAt)
+	assert.Equal(s.T(), decisions[2], MostRestrictiveDecision(decisions))
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecisions_RejectedStillCounts() {
+	config := &LimiterConfig{
+		IP:             &RateConfig{MaxRequestsPerSecond: 1, BlockTimeMilliseconds: 100},
+		Token:          &RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 100},
+		StorageAdapter: adapters.NewRateLimitMemoryStorageAdapter(),
+	}
+	checks := []*RateLimitCheck{
+		{KeyType: "TOKEN", Key: "abc", RuleName: "token", RateConfig: config.Token},
+		{KeyType: "IP", Key: "127.0.0.1", RuleName: "ip", RateConfig: config.IP},
+	}
+
+	decisions, err := CheckRateLimitDecisions(s.context, checks, config)
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), MostRestrictiveDecision(decisions).Allowed)
+	assert.Equal(s.T(), int64(9), decisions[0].Remaining)
+
+	// The IP limit rejects the request, and the token limit still counts it.
+	decisions, err = CheckRateLimitDecisions(s.context, checks, config)
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), MostRestrictiveDecision(decisions).Allowed)
+	assert.False(s.T(), decisions[1].Allowed)
+	assert.True(s.T(), decisions[0].Allowed)
+	assert.Equal(s.T(), int64(8), decisions[0].Remaining)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecision_BucketsWithLimitsOnly() {
+	for _, algorithm := range []string{AlgorithmTokenBucket, AlgorithmGCRA} {
+		rateConfig := &RateConfig{Algorithm: algorithm, BlockTimeMilliseconds: 100, Limits: []*WindowLimit{{MaxRequests: 120, WindowMilliseconds: 60000}}}
+		config := &LimiterConfig{IP: rateConfig, StorageAdapter: adapters.NewRateLimitMemoryStorageAdapter()}
+
+		decision, err := CheckRateLimitDecision(s.context, "IP", "127.0.0.1", "ip", config, rateConfig)
+		assert.Nil(s.T(), err, algorithm)
+		assert.True(s.T(), decision.Allowed, algorithm)
+		assert.Equal(s.T(), int64(120), decision.Limit, algorithm)
+	}
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecisions_Fallback() {
+	context := s.context
+	config := &LimiterConfig{
+		IP:    &RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 100},
+		Token: &RateConfig{MaxRequestsPerSecond: 20, BlockTimeMilliseconds: 100},
+	}
+	checks := []*RateLimitCheck{
+		{KeyType: "TOKEN", Key: "abc", RuleName: "token", RateConfig: config.Token},
+		{KeyType: "IP", Key: "127.0.0.1", RuleName: "ip", RateConfig: config.IP},
+	}
+
+	s.checkAdapterMock.EXPECT().
+		Check(context, "TOKEN", "abc", gomock.Any()).
+		Return(&adapters.CheckResult{Success: true, ExceededIndex: -1, Counts: []int64{5}}, nil).Times(1)
+	s.checkAdapterMock.EXPECT().
+		Check(context, "IP", "127.0.0.1", gomock.Any()).
+		Return(&adapters.CheckResult{Success: true, ExceededIndex: -1, Counts: []int64{9}}, nil).Times(1)
+
+	config.StorageAdapter = &checkStorageAdapterMock{s.storageAdapterMock, s.checkAdapterMock}
+
+	decisions, err := CheckRateLimitDecisions(context, checks, config)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), decisions, 2)
+	assert.Equal(s.T(), int64(15), decisions[0].Remaining)
+	assert.Equal(s.T(), int64(1), decisions[1].Remaining)
+	assert.Equal(s.T(), decisions[1], MostRestrictiveDecision(decisions))
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecisions_Scope() {
+	config := &LimiterConfig{IP: &RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 100}}
+	loginConfig := &RateConfig{MaxRequestsPerSecond: 2, BlockTimeMilliseconds: 100}
+	checks := []*RateLimitCheck{{KeyType: "IP", Key: "127.0.0.1", RuleName: "routes.login", RateConfig: loginConfig, Scope: "ROUTE-login"}}
+
+	s.checkAdapterMock.EXPECT().
+		Check(s.context, "ROUTE-login-IP", "127.0.0.1", gomock.Any()).
+		Return(&adapters.CheckResult{Success: true, ExceededIndex: -1, Counts: []int64{1}}, nil).Times(1)
+
+	config.StorageAdapter = &checkStorageAdapterMock{s.storageAdapterMock, s.checkAdapterMock}
+
+	decisions, err := CheckRateLimitDecisions(s.context, checks, config)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), decisions, 1)
+	assert.Equal(s.T(), "routes.login", decisions[0].RuleName)
+	assert.Equal(s.T(), int64(1), decisions[0].Remaining)
+}
+
+func (s *RateLimiterTestSuite) TestCheckRateLimitDecisions_Error() {
+	config := &LimiterConfig{IP: &RateConfig{MaxRequestsPerSecond: 10, BlockTimeMilliseconds: 100}}
+	checks := []*RateLimitCheck{{KeyType: "IP", Key: "127.0.0.1", RuleName: "ip", RateConfig: config.IP}}
+
+	s.batchCheckAdapterMock.EXPECT().CheckBatch(s.context, gomock.Any()).Return(nil, errors.New("error")).Times(1)
+
+	config.StorageAdapter = &batchCheckStorageAdapterMock{s.storageAdapterMock, s.batchCheckAdapterMock}
+
+	decisions, err := CheckRateLimitDecisions(s.context, checks, config)
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), decisions)
+}
+
+func (s *RateLimiterTestSuite) TestMostRestrictiveDecision() {
+	now := time.Now()
+	allowed := &RateLimitDecision{Allowed: true, Remaining: 5}
+	tighter := &RateLimitDecision{Allowed: true, Remaining: 1}
+	denied := &RateLimitDecision{Allowed: false, ResetAt: now.Add(time.Second)}
+	longer := &RateLimitDecision{Allowed: false, ResetAt: now.Add(time.Minute)}
+
+	assert.Nil(s.T(), MostRestrictiveDecision(nil))
+	assert.Nil(s.T(), MostRestrictiveDecision([]*RateLimitDecision{nil}))
+	assert.Equal(s.T(), tighter, MostRestrictiveDecision([]*RateLimitDecision{allowed, nil, tighter}))
+	assert.Equal(s.T(), denied, MostRestrictiveDecision([]*RateLimitDecision{allowed, denied, tighter}))
+	assert.Equal(s.T(), longer, MostRestrictiveDecision([]*RateLimitDecision{denied, allowed, longer}))
+}
@@ -0,0 +1,17 @@
+package response_writer
+
+import "time"
+
+type RateLimitDecision struct {
+	Allowed            bool          `json:"allowed"`
+	Limit              int64         `json:"limit"`
+	Remaining          int64         `json:"remaining"`
+	WindowMilliseconds int64         `json:"windowMilliseconds"`
+	ResetAt            time.Time     `json:"resetAt"`
+	BlockedUntil       *time.Time    `json:"blockedUntil"`
+	RetryAfter         time.Duration `json:"retryAfter"`
+	KeyType            string        `json:"keyType"`
+	Key                string        `json:"key"`
+	RuleName           string        `json:"ruleName"`
+	MatchedRules       []string      `json:"matchedRules"`
+}
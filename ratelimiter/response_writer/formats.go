@@ -0,0 +1,283 @@
+package response_writer
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+const ContentTypeProblemJSON = "application/problem+json"
+const ContentTypeJSON = "application/json"
+const ContentTypeText = "text/plain"
+const ContentTypeHTML = "text/html"
+
+// ResponseTemplates holds the templates used by the built-in writers. Title, Detail and Text are text/template, HTML is html/template.
+type ResponseTemplates struct {
+	ProblemType        string `json:"problemType"`
+	Title              string `json:"title"`
+	Detail             string `json:"detail"`
+	ErrorTitle         string `json:"errorTitle"`
+	ErrorDetail        string `json:"errorDetail"`
+	DeniedTitle        string `json:"deniedTitle"`
+	DeniedDetail       string `json:"deniedDetail"`
+	UnauthorizedTitle  string `json:"unauthorizedTitle"`
+	UnauthorizedDetail string `json:"unauthorizedDetail"`
+	Text               string `json:"text"`
+	HTML               string `json:"html"`
+}
+
+type ResponseTemplateData struct {
+	Status     int
+	Title      string
+	Detail     string
+	RetryAfter int64
+	Limit      int64
+	Remaining  int64
+	ResetAt    time.Time
+	Path       string
+}
+
+func DefaultResponseTemplates() *ResponseTemplates {
+	return &ResponseTemplates{
+		ProblemType:        "about:blank",
+		Title:              "Too Many Requests",
+		Detail:             "you have reached the maximum number of requests or actions allowed within a certain time frame",
+		ErrorTitle:         "Internal Server Error",
+		ErrorDetail:        "internal server error",
+		DeniedTitle:        "Forbidden",
+		DeniedDetail:       "access denied",
+		UnauthorizedTitle:  "Unauthorized",
+		UnauthorizedDetail: "unknown API token",
+		Text:               "{{.Detail}}",
+		HTML:               "<!DOCTYPE html><html><head><title>{{.Title}}</title></head><body><h1>{{.Title}}</h1><p>{{.Detail}}</p></body></html>",
+	}
+}
+
+type parsedResponseTemplates struct {
+	problemType        string
+	customDetails      map[string]bool
+	title              *template.Template
+	detail             *template.Template
+	errorTitle         *template.Template
+	errorDetail        *template.Template
+	deniedTitle        *template.Template
+	deniedDetail       *template.Template
+	unauthorizedTitle  *template.Template
+	unauthorizedDetail *template.Template
+	text               *template.Template
+	html               *htmltemplate.Template
+}
+
+// rateLimiterFormatResponseWriter takes the detail of blocked, denied and unauthorized responses from the catalog, in the
+// language of the request, unless the templates set their own.
+type rateLimiterFormatResponseWriter struct {
+	contentType string
+	templates   *parsedResponseTemplates
+	catalog     *MessageCatalog
+	render      func(templates *parsedResponseTemplates, data *ResponseTemplateData) ([]byte, error)
+}
+
+func NewRateLimiterProblemJSONResponseWriter(templates *ResponseTemplates) (*rateLimiterFormatResponseWriter, error) {
+	return newRateLimiterFormatResponseWriter(ContentTypeProblemJSON, templates, NewMessageCatalog(), renderProblemJSON)
+}
+
+func NewRateLimiterJSONResponseWriter(templates *ResponseTemplates) (*rateLimiterFormatResponseWriter, error) {
+	return newRateLimiterFormatResponseWriter(ContentTypeJSON, templates, NewMessageCatalog(), renderJSON)
+}
+
+func NewRateLimiterTextResponseWriter(templates *ResponseTemplates) (*rateLimiterFormatResponseWriter, error) {
+	return newRateLimiterFormatResponseWriter(ContentTypeText, templates, NewMessageCatalog(), renderText)
+}
+
+func NewRateLimiterHTMLResponseWriter(templates *ResponseTemplates) (*rateLimiterFormatResponseWriter, error) {
+	return newRateLimiterFormatResponseWriter(ContentTypeHTML, templates, NewMessageCatalog(), renderHTML)
+}
+
+func newRateLimiterFormatResponseWriter(contentType string, templates *ResponseTemplates, catalog *MessageCatalog, render func(templates *parsedResponseTemplates, data *ResponseTemplateData) ([]byte, error)) (*rateLimiterFormatResponseWriter, error) {
+	parsed, err := parseResponseTemplates(templates)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimiterFormatResponseWriter{contentType: contentType, templates: parsed, catalog: catalog, render: render}, nil
+}
+
+func (rw *rateLimiterFormatResponseWriter) WriteResponse(w *http.ResponseWriter) error {
+	return rw.WriteBlockedResponse(*w, nil, &RateLimitDecision{})
+}
+
+func (rw *rateLimiterFormatResponseWriter) WriteBlockedResponse(w http.ResponseWriter, r *http.Request, decision *RateLimitDecision) error {
+	data := &ResponseTemplateData{
+		Status:     http.StatusTooManyRequests,
+		RetryAfter: ceilSeconds(decision.RetryAfter),
+		Limit:      decision.Limit,
+		Remaining:  decision.Remaining,
+		ResetAt:    decision.ResetAt,
+	}
+	return rw.writeLocalized(w, r, MessageBlocked, data, rw.templates.title, rw.templates.detail)
+}
+
+func (rw *rateLimiterFormatResponseWriter) WriteError(w *http.ResponseWriter, err error) error {
+	return rw.WriteErrorResponse(*w, nil, nil)
+}
+
+func (rw *rateLimiterFormatResponseWriter) WriteErrorResponse(w http.ResponseWriter, r *http.Request, err *RateLimitError) error {
+	data := &ResponseTemplateData{Status: http.StatusInternalServerError}
+	if r != nil {
+		data.Path = r.URL.Path
+	}
+	return rw.write(&w, data, rw.templates.errorTitle, rw.templates.errorDetail)
+}
+
+func (rw *rateLimiterFormatResponseWriter) WriteDeniedResponse(w http.ResponseWriter, r *http.Request) error {
+	data := &ResponseTemplateData{Status: http.StatusForbidden}
+	return rw.writeLocalized(w, r, MessageDenied, data, rw.templates.deniedTitle, rw.templates.deniedDetail)
+}
+
+func (rw *rateLimiterFormatResponseWriter) WriteUnauthorizedResponse(w http.ResponseWriter, r *http.Request) error {
+	data := &ResponseTemplateData{Status: http.StatusUnauthorized}
+	return rw.writeLocalized(w, r, MessageUnauthorized, data, rw.templates.unauthorizedTitle, rw.templates.unauthorizedDetail)
+}
+
+func (rw *rateLimiterFormatResponseWriter) writeLocalized(w http.ResponseWriter, r *http.Request, kind string, data *ResponseTemplateData, title *template.Template, detail *template.Template) error {
+	if r != nil {
+		data.Path = r.URL.Path
+	}
+	if r == nil || rw.templates.customDetails[kind] {
+		return rw.write(&w, data, title, detail)
+	}
+
+	language := rw.catalog.LanguageFor(kind, r.Header.Get("Accept-Language"))
+	message, err := rw.catalog.MessageFor(kind, language, data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Language", language)
+	return rw.writeDetail(&w, data, title, message)
+}
+
+func (rw *rateLimiterFormatResponseWriter) write(w *http.ResponseWriter, data *ResponseTemplateData, title *template.Template, detail *template.Template) error {
+	value, err := executeTemplate(detail, data)
+	if err != nil {
+		return err
+	}
+	return rw.writeDetail(w, data, title, value)
+}
+
+func (rw *rateLimiterFormatResponseWriter) writeDetail(w *http.ResponseWriter, data *ResponseTemplateData, title *template.Template, detail string) error {
+	var err error
+
+	data.Title, err = executeTemplate(title, data)
+	if err != nil {
+		return err
+	}
+	data.Detail = detail
+
+	body, err := rw.render(rw.templates, data)
+	if err != nil {
+		return err
+	}
+
+	(*w).Header().Set("Content-Type", rw.contentType+"; charset=utf-8")
+	(*w).WriteHeader(data.Status)
+	(*w).Write(body)
+	return nil
+}
+
+func renderProblemJSON(templates *parsedResponseTemplates, data *ResponseTemplateData) ([]byte, error) {
+	problem := struct {
+		Type       string `json:"type"`
+		Title      string `json:"title"`
+		Status     int    `json:"status"`
+		Detail     string `json:"detail"`
+		Instance   string `json:"instance,omitempty"`
+		RetryAfter int64  `json:"retryAfter,omitempty"`
+	}{templates.problemType, data.Title, data.Status, data.Detail, data.Path, data.RetryAfter}
+	return json.Marshal(problem)
+}
+
+func renderJSON(templates *parsedResponseTemplates, data *ResponseTemplateData) ([]byte, error) {
+	response := struct {
+		Error      string `json:"error"`
+		Message    string `json:"message"`
+		RetryAfter int64  `json:"retryAfter,omitempty"`
+		Limit      int64  `json:"limit,omitempty"`
+	}{data.Title, data.Detail, data.RetryAfter, data.Limit}
+	return json.Marshal(response)
+}
+
+func renderText(templates *parsedResponseTemplates, data *ResponseTemplateData) ([]byte, error) {
+	body := &bytes.Buffer{}
+	err := templates.text.Execute(body, data)
+	return body.Bytes(), err
+}
+
+func renderHTML(templates *parsedResponseTemplates, data *ResponseTemplateData) ([]byte, error) {
+	body := &bytes.Buffer{}
+	err := templates.html.Execute(body, data)
+	return body.Bytes(), err
+}
+
+func parseResponseTemplates(templates *ResponseTemplates) (*parsedResponseTemplates, error) {
+	defaults := DefaultResponseTemplates()
+	if templates == nil {
+		templates = defaults
+	}
+
+	parsed := &parsedResponseTemplates{
+		problemType: valueOrDefault(templates.ProblemType, defaults.ProblemType),
+		customDetails: map[string]bool{
+			MessageBlocked:      templates.Detail != "" && templates.Detail != defaults.Detail,
+			MessageDenied:       templates.DeniedDetail != "" && templates.DeniedDetail != defaults.DeniedDetail,
+			MessageUnauthorized: templates.UnauthorizedDetail != "" && templates.UnauthorizedDetail != defaults.UnauthorizedDetail,
+		},
+	}
+
+	textTemplates := []struct {
+		target   **template.Template
+		name     string
+		value    string
+		fallback string
+	}{
+		{&parsed.title, "title", templates.Title, defaults.Title},
+		{&parsed.detail, "detail", templates.Detail, defaults.Detail},
+		{&parsed.errorTitle, "errorTitle", templates.ErrorTitle, defaults.ErrorTitle},
+		{&parsed.errorDetail, "errorDetail", templates.ErrorDetail, defaults.ErrorDetail},
+		{&parsed.deniedTitle, "deniedTitle", templates.DeniedTitle, defaults.DeniedTitle},
+		{&parsed.deniedDetail, "deniedDetail", templates.DeniedDetail, defaults.DeniedDetail},
+		{&parsed.unauthorizedTitle, "unauthorizedTitle", templates.UnauthorizedTitle, defaults.UnauthorizedTitle},
+		{&parsed.unauthorizedDetail, "unauthorizedDetail", templates.UnauthorizedDetail, defaults.UnauthorizedDetail},
+		{&parsed.text, "text", templates.Text, defaults.Text},
+	}
+
+	var err error
+	for _, textTemplate := range textTemplates {
+		*textTemplate.target, err = template.New(textTemplate.name).Parse(valueOrDefault(textTemplate.value, textTemplate.fallback))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	parsed.html, err = htmltemplate.New("html").Parse(valueOrDefault(templates.HTML, defaults.HTML))
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+func executeTemplate(tmpl *template.Template, data *ResponseTemplateData) (string, error) {
+	value := &bytes.Buffer{}
+	err := tmpl.Execute(value, data)
+	return value.String(), err
+}
+
+func valueOrDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
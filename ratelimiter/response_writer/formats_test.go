@@ -0,0 +1,212 @@
+package response_writer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimiterFormatResponseWriterTestSuite struct {
+	suite.Suite
+}
+
+func TestRateLimiterFormatResponseWriterTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimiterFormatResponseWriterTestSuite))
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) write(responseWriter RateLimiterRequestResponseWriter, decision *RateLimitDecision) (*http.Response, string) {
+	recorder := httptest.NewRecorder()
+
+	err := responseWriter.WriteBlockedResponse(recorder, nil, decision)
+	assert.Nil(s.T(), err)
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+
+	return response, string(responseBody)
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestProblemJSON() {
+	responseWriter, err := NewRateLimiterProblemJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+
+	response, body := s.write(responseWriter, &RateLimitDecision{Limit: 10, RetryAfter: time.Millisecond * 1500})
+
+	problem := map[string]interface{}{}
+	assert.Nil(s.T(), json.Unmarshal([]byte(body), &problem))
+	assert.Equal(s.T(), 429, response.StatusCode)
+	assert.Equal(s.T(), "application/problem+json; charset=utf-8", response.Header.Get("Content-Type"))
+	assert.Equal(s.T(), "about:blank", problem["type"])
+	assert.Equal(s.T(), "Too Many Requests", problem["title"])
+	assert.Equal(s.T(), float64(429), problem["status"])
+	assert.Equal(s.T(), "you have reached the maximum number of requests or actions allowed within a certain time frame", problem["detail"])
+	assert.Equal(s.T(), float64(2), problem["retryAfter"])
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestJSON_CustomTemplates() {
+	responseWriter, err := NewRateLimiterJSONResponseWriter(&ResponseTemplates{Detail: "limit of {{.Limit}} reached, retry in {{.RetryAfter}}s"})
+	assert.Nil(s.T(), err)
+
+	response, body := s.write(responseWriter, &RateLimitDecision{Limit: 10, RetryAfter: time.Second * 3})
+
+	assert.Equal(s.T(), "application/json; charset=utf-8", response.Header.Get("Content-Type"))
+	assert.JSONEq(s.T(), `{"error":"Too Many Requests","message":"limit of 10 reached, retry in 3s","retryAfter":3,"limit":10}`, body)
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestJSON_LocalizedDetail() {
+	responseWriter, err := NewRateLimiterJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/orders", nil)
+	request.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+	responseWriter.WriteBlockedResponse(recorder, request, &RateLimitDecision{Limit: 10, RetryAfter: time.Second * 3})
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "pt-BR", response.Header.Get("Content-Language"))
+	assert.JSONEq(s.T(), `{"error":"Too Many Requests","message":"você atingiu o limite de 10 requisições permitidas em um determinado período, tente novamente em 3 segundos","retryAfter":3,"limit":10}`, string(responseBody))
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestHTML_CatalogFromNegotiator() {
+	catalog := NewMessageCatalog()
+	assert.Nil(s.T(), catalog.Add("fr", "trop de requêtes"))
+	negotiator, err := NewRateLimiterContentNegotiatorWithCatalog(nil, catalog)
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/orders", nil)
+	request.Header.Set("Accept-Language", "fr-FR")
+	negotiator.Select("text/html,application/xhtml+xml,*/*;q=0.8").(RateLimiterRequestResponseWriter).
+		WriteBlockedResponse(recorder, request, &RateLimitDecision{})
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "fr", response.Header.Get("Content-Language"))
+	assert.Contains(s.T(), string(responseBody), "<p>trop de requêtes</p>")
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestJSON_CustomDetailNotLocalized() {
+	responseWriter, err := NewRateLimiterJSONResponseWriter(&ResponseTemplates{Detail: "limit of {{.Limit}} reached"})
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/orders", nil)
+	request.Header.Set("Accept-Language", "pt-BR")
+	responseWriter.WriteBlockedResponse(recorder, request, &RateLimitDecision{Limit: 10})
+
+	assert.Empty(s.T(), recorder.Result().Header.Get("Content-Language"))
+	assert.JSONEq(s.T(), `{"error":"Too Many Requests","message":"limit of 10 reached","limit":10}`, recorder.Body.String())
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestText() {
+	responseWriter, err := NewRateLimiterTextResponseWriter(&ResponseTemplates{Text: "{{.Title}}: retry in {{.RetryAfter}}s"})
+	assert.Nil(s.T(), err)
+
+	response, body := s.write(responseWriter, &RateLimitDecision{RetryAfter: time.Second})
+
+	assert.Equal(s.T(), "text/plain; charset=utf-8", response.Header.Get("Content-Type"))
+	assert.Equal(s.T(), "Too Many Requests: retry in 1s", body)
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestHTML_EscapesValues() {
+	responseWriter, err := NewRateLimiterHTMLResponseWriter(&ResponseTemplates{Title: "<script>"})
+	assert.Nil(s.T(), err)
+
+	response, body := s.write(responseWriter, &RateLimitDecision{})
+
+	assert.Equal(s.T(), "text/html; charset=utf-8", response.Header.Get("Content-Type"))
+	assert.Contains(s.T(), body, "<h1>&lt;script&gt;</h1>")
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestWriteError() {
+	responseWriter, err := NewRateLimiterProblemJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	writer := http.ResponseWriter(recorder)
+	responseWriter.WriteError(&writer, errors.New("error"))
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 500, response.StatusCode)
+	assert.JSONEq(s.T(), `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`, string(responseBody))
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestWriteDeniedResponse() {
+	responseWriter, err := NewRateLimiterProblemJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	responseWriter.WriteDeniedResponse(recorder, httptest.NewRequest("GET", "http://testing/orders", nil))
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 403, response.StatusCode)
+	assert.JSONEq(s.T(), `{"type":"about:blank","title":"Forbidden","status":403,"detail":"access denied","instance":"/orders"}`, string(responseBody))
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestWriteDeniedResponse_Localized() {
+	responseWriter, err := NewRateLimiterJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/orders", nil)
+	request.Header.Set("Accept-Language", "es-AR")
+	responseWriter.WriteDeniedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 403, response.StatusCode)
+	assert.Equal(s.T(), "es", response.Header.Get("Content-Language"))
+	assert.JSONEq(s.T(), `{"error":"Forbidden","message":"acceso denegado"}`, string(responseBody))
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestWriteUnauthorizedResponse() {
+	responseWriter, err := NewRateLimiterJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	responseWriter.WriteUnauthorizedResponse(recorder, httptest.NewRequest("GET", "http://testing/orders", nil))
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 401, response.StatusCode)
+	assert.JSONEq(s.T(), `{"error":"Unauthorized","message":"unknown API token"}`, string(responseBody))
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestWriteUnauthorizedResponse_Localized() {
+	responseWriter, err := NewRateLimiterProblemJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/orders", nil)
+	request.Header.Set("Accept-Language", "pt")
+	responseWriter.WriteUnauthorizedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "pt-BR", response.Header.Get("Content-Language"))
+	assert.JSONEq(s.T(), `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"token de API desconhecido","instance":"/orders"}`, string(responseBody))
+}
+
+func (s *RateLimiterFormatResponseWriterTestSuite) TestInvalidTemplate() {
+	responseWriter, err := NewRateLimiterTextResponseWriter(&ResponseTemplates{Text: "{{.Title"})
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), responseWriter)
+}
@@ -0,0 +1,49 @@
+package response_writer
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const HeadersModeIETF = "ietf"
+const HeadersModeLegacy = "legacy"
+const HeadersModeNone = "none"
+
+func WriteRateLimitHeaders(w http.ResponseWriter, decision *RateLimitDecision, mode string) {
+	if decision == nil {
+		return
+	}
+
+	now := time.Now()
+	header := w.Header()
+
+	switch mode {
+	case HeadersModeIETF:
+		header.Set("RateLimit-Limit", strconv.FormatInt(decision.Limit, 10))
+		header.Set("RateLimit-Remaining", strconv.FormatInt(decision.Remaining, 10))
+		header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(decision.ResetAt.Sub(now)), 10))
+		if decision.WindowMilliseconds > 0 {
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", decision.Limit, ceilSeconds(time.Duration(decision.WindowMilliseconds)*time.Millisecond)))
+		}
+	case HeadersModeLegacy:
+		header.Set("X-RateLimit-Limit", strconv.FormatInt(decision.Limit, 10))
+		header.Set("X-RateLimit-Remaining", strconv.FormatInt(decision.Remaining, 10))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(float64(decision.ResetAt.UnixMilli())/1000)), 10))
+	default:
+		return
+	}
+
+	if !decision.Allowed {
+		header.Set("Retry-After", strconv.FormatInt(ceilSeconds(decision.RetryAfter), 10))
+	}
+}
+
+func ceilSeconds(duration time.Duration) int64 {
+	if duration <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,78 @@
+package response_writer
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WriteRateLimitHeadersTestSuite struct {
+	suite.Suite
+}
+
+func TestWriteRateLimitHeadersTestSuite(t *testing.T) {
+	suite.Run(t, new(WriteRateLimitHeadersTestSuite))
+}
+
+func (s *WriteRateLimitHeadersTestSuite) TestIETFAllowed() {
+	recorder := httptest.NewRecorder()
+	decision := &RateLimitDecision{Allowed: true, Limit: 100, Remaining: 42, WindowMilliseconds: 60000, ResetAt: time.Now().Add(time.Millisecond * 2500)}
+
+	WriteRateLimitHeaders(recorder, decision, HeadersModeIETF)
+
+	header := recorder.Header()
+	assert.Equal(s.T(), "100", header.Get("RateLimit-Limit"))
+	assert.Equal(s.T(), "42", header.Get("RateLimit-Remaining"))
+	assert.Equal(s.T(), "3", header.Get("RateLimit-Reset"))
+	assert.Equal(s.T(), "100;w=60", header.Get("RateLimit-Policy"))
+	assert.Empty(s.T(), header.Get("Retry-After"))
+	assert.Empty(s.T(), header.Get("X-RateLimit-Limit"))
+}
+
+func (s *WriteRateLimitHeadersTestSuite) TestIETFDenied() {
+	recorder := httptest.NewRecorder()
+	decision := &RateLimitDecision{Allowed: false, Limit: 10, WindowMilliseconds: 1000, ResetAt: time.Now().Add(time.Millisecond * 1500), RetryAfter: time.Millisecond * 1500}
+
+	WriteRateLimitHeaders(recorder, decision, HeadersModeIETF)
+
+	header := recorder.Header()
+	assert.Equal(s.T(), "10", header.Get("RateLimit-Limit"))
+	assert.Equal(s.T(), "0", header.Get("RateLimit-Remaining"))
+	assert.Equal(s.T(), "2", header.Get("Retry-After"))
+}
+
+func (s *WriteRateLimitHeadersTestSuite) TestLegacy() {
+	recorder := httptest.NewRecorder()
+	resetAt := time.Unix(1700000000, 0)
+	decision := &RateLimitDecision{Allowed: false, Limit: 10, WindowMilliseconds: 1000, ResetAt: resetAt, RetryAfter: time.Second}
+
+	WriteRateLimitHeaders(recorder, decision, HeadersModeLegacy)
+
+	header := recorder.Header()
+	assert.Equal(s.T(), "10", header.Get("X-RateLimit-Limit"))
+	assert.Equal(s.T(), "0", header.Get("X-RateLimit-Remaining"))
+	assert.Equal(s.T(), strconv.FormatInt(resetAt.Unix(), 10), header.Get("X-RateLimit-Reset"))
+	assert.Equal(s.T(), "1", header.Get("Retry-After"))
+	assert.Empty(s.T(), header.Get("RateLimit-Limit"))
+}
+
+func (s *WriteRateLimitHeadersTestSuite) TestNone() {
+	recorder := httptest.NewRecorder()
+	decision := &RateLimitDecision{Allowed: false, Limit: 10, RetryAfter: time.Second}
+
+	WriteRateLimitHeaders(recorder, decision, HeadersModeNone)
+
+	assert.Empty(s.T(), recorder.Header())
+}
+
+func (s *WriteRateLimitHeadersTestSuite) TestNilDecision() {
+	recorder := httptest.NewRecorder()
+
+	WriteRateLimitHeaders(recorder, nil, HeadersModeIETF)
+
+	assert.Empty(s.T(), recorder.Header())
+}
@@ -0,0 +1,204 @@
+package response_writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const DefaultLanguage = "en"
+
+// The kinds of message in a MessageCatalog, one for each response the middleware writes on its own.
+const MessageBlocked = "blocked"
+const MessageDenied = "denied"
+const MessageUnauthorized = "unauthorized"
+
+var messageStatuses = map[string]int{
+	MessageBlocked:      http.StatusTooManyRequests,
+	MessageDenied:       http.StatusForbidden,
+	MessageUnauthorized: http.StatusUnauthorized,
+}
+
+var builtinMessages = map[string]map[string]string{
+	MessageBlocked: {
+		"pt-BR": "você atingiu o limite de {{.Limit}} requisições permitidas em um determinado período, tente novamente em {{.RetryAfter}} segundos",
+		"en":    "you have reached the limit of {{.Limit}} requests allowed within a certain time frame, try again in {{.RetryAfter}} seconds",
+		"es":    "has alcanzado el límite de {{.Limit}} solicitudes permitidas en un determinado período de tiempo, inténtalo de nuevo en {{.RetryAfter}} segundos",
+	},
+	MessageDenied: {
+		"pt-BR": "acesso negado",
+		"en":    "access denied",
+		"es":    "acceso denegado",
+	},
+	MessageUnauthorized: {
+		"pt-BR": "token de API desconhecido",
+		"en":    "unknown API token",
+		"es":    "token de API desconocido",
+	},
+}
+
+var defaultMessageCatalog = NewMessageCatalog()
+
+type catalogMessage struct {
+	language string
+	template *template.Template
+}
+
+// MessageCatalog holds each kind of message for each language as a text/template over ResponseTemplateData.
+type MessageCatalog struct {
+	defaultLanguage string
+	messages        map[string]map[string]*catalogMessage
+}
+
+func NewMessageCatalog() *MessageCatalog {
+	catalog := &MessageCatalog{defaultLanguage: DefaultLanguage, messages: map[string]map[string]*catalogMessage{}}
+	for kind, messages := range builtinMessages {
+		for language, message := range messages {
+			catalog.AddMessage(kind, language, message)
+		}
+	}
+	return catalog
+}
+
+func LoadMessageCatalogFile(path string) (*MessageCatalog, error) {
+	catalog := NewMessageCatalog()
+	err := catalog.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// Add adds or replaces the block message of a language.
+func (c *MessageCatalog) Add(language string, message string) error {
+	return c.AddMessage(MessageBlocked, language, message)
+}
+
+func (c *MessageCatalog) AddMessage(kind string, language string, message string) error {
+	parsed, err := template.New(kind + "-" + language).Parse(message)
+	if err != nil {
+		return err
+	}
+	if c.messages[kind] == nil {
+		c.messages[kind] = map[string]*catalogMessage{}
+	}
+	c.messages[kind][strings.ToLower(language)] = &catalogMessage{language: language, template: parsed}
+	return nil
+}
+
+// LoadFile adds or replaces messages from a JSON object of language to block message, like {"fr": "..."}, or to an
+// object with a message for each kind, like {"fr": {"blocked": "...", "denied": "..."}}.
+func (c *MessageCatalog) LoadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	languages := map[string]json.RawMessage{}
+	err = json.Unmarshal(content, &languages)
+	if err != nil {
+		return err
+	}
+
+	for language, value := range languages {
+		messages := map[string]string{}
+		var message string
+		if json.Unmarshal(value, &message) == nil {
+			messages[MessageBlocked] = message
+		} else if err = json.Unmarshal(value, &messages); err != nil {
+			return fmt.Errorf("invalid messages of language \"%s\": %w", language, err)
+		}
+
+		for kind, message := range messages {
+			err = c.AddMessage(kind, language, message)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Language picks the best language of the block message for an Accept-Language header.
+func (c *MessageCatalog) Language(acceptLanguage string) string {
+	return c.LanguageFor(MessageBlocked, acceptLanguage)
+}
+
+// LanguageFor picks the best language of a kind of message for an Accept-Language header, matching "pt" or "pt-PT" to
+// "pt-BR" when only that one exists.
+func (c *MessageCatalog) LanguageFor(kind string, acceptLanguage string) string {
+	messages := c.messages[kind]
+	for _, accepted := range parseAccept(acceptLanguage) {
+		if accepted.mediaType == "*" {
+			break
+		}
+
+		if message, ok := messages[accepted.mediaType]; ok {
+			return message.language
+		}
+
+		base, _, _ := strings.Cut(accepted.mediaType, "-")
+		if message, ok := messages[base]; ok {
+			return message.language
+		}
+
+		for _, key := range sortedKeys(messages) {
+			if strings.HasPrefix(key, base+"-") {
+				return messages[key].language
+			}
+		}
+	}
+
+	return c.defaultLanguage
+}
+
+func sortedKeys(messages map[string]*catalogMessage) []string {
+	keys := []string{}
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *MessageCatalog) Message(language string, data *ResponseTemplateData) (string, error) {
+	return c.MessageFor(MessageBlocked, language, data)
+}
+
+func (c *MessageCatalog) MessageFor(kind string, language string, data *ResponseTemplateData) (string, error) {
+	message, ok := c.messages[kind][strings.ToLower(language)]
+	if !ok {
+		message, ok = c.messages[kind][strings.ToLower(c.defaultLanguage)]
+	}
+	if !ok {
+		return "", fmt.Errorf("no %s message in the catalog", kind)
+	}
+
+	value := &bytes.Buffer{}
+	err := message.template.Execute(value, data)
+	return value.String(), err
+}
+
+// writeCatalogResponse answers with the message of a kind in the language of the request, as plain text.
+func writeCatalogResponse(w http.ResponseWriter, r *http.Request, catalog *MessageCatalog, kind string, data *ResponseTemplateData) error {
+	language := catalog.LanguageFor(kind, r.Header.Get("Accept-Language"))
+
+	data.Status = messageStatuses[kind]
+	data.Path = r.URL.Path
+	message, err := catalog.MessageFor(kind, language, data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Language", language)
+	w.WriteHeader(data.Status)
+	w.Write([]byte(message))
+	return nil
+}
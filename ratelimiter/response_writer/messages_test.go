@@ -0,0 +1,120 @@
+package response_writer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MessageCatalogTestSuite struct {
+	suite.Suite
+}
+
+func TestMessageCatalogTestSuite(t *testing.T) {
+	suite.Run(t, new(MessageCatalogTestSuite))
+}
+
+func (s *MessageCatalogTestSuite) TestLanguage() {
+	catalog := NewMessageCatalog()
+
+	expected := map[string]string{
+		"":                              "en",
+		"pt-BR":                         "pt-BR",
+		"pt-br,en;q=0.5":                "pt-BR",
+		"pt":                            "pt-BR",
+		"pt-PT":                         "pt-BR",
+		"es-AR,es;q=0.9":                "es",
+		"en-US,en;q=0.9":                "en",
+		"fr-FR,fr;q=0.9,es;q=0.8":       "es",
+		"de":                            "en",
+		"*":                             "en",
+		"es;q=0.2,pt-BR;q=0.8,en;q=0.5": "pt-BR",
+	}
+
+	for acceptLanguage, language := range expected {
+		assert.Equal(s.T(), language, catalog.Language(acceptLanguage), acceptLanguage)
+	}
+}
+
+func (s *MessageCatalogTestSuite) TestMessage() {
+	catalog := NewMessageCatalog()
+	data := &ResponseTemplateData{Limit: 10, RetryAfter: 3}
+
+	message, err := catalog.Message("pt-BR", data)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "você atingiu o limite de 10 requisições permitidas em um determinado período, tente novamente em 3 segundos", message)
+
+	message, err = catalog.Message("en", data)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "you have reached the limit of 10 requests allowed within a certain time frame, try again in 3 seconds", message)
+
+	message, err = catalog.Message("es", data)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "has alcanzado el límite de 10 solicitudes permitidas en un determinado período de tiempo, inténtalo de nuevo en 3 segundos", message)
+}
+
+func (s *MessageCatalogTestSuite) TestLoadFile() {
+	path := filepath.Join(s.T().TempDir(), "messages.json")
+	os.WriteFile(path, []byte(`{"fr": "limite de {{.Limit}} atteinte, réessayez dans {{.RetryAfter}} secondes", "en": "slow down"}`), 0644)
+
+	catalog, err := LoadMessageCatalogFile(path)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "fr", catalog.Language("fr-CA"))
+
+	message, err := catalog.Message("fr", &ResponseTemplateData{Limit: 5, RetryAfter: 2})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "limite de 5 atteinte, réessayez dans 2 secondes", message)
+
+	message, err = catalog.Message("en", &ResponseTemplateData{})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "slow down", message)
+}
+
+func (s *MessageCatalogTestSuite) TestLoadFile_Invalid() {
+	path := filepath.Join(s.T().TempDir(), "messages.json")
+	os.WriteFile(path, []byte(`{"fr": "{{.Limit"}`), 0644)
+
+	catalog, err := LoadMessageCatalogFile(path)
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), catalog)
+
+	catalog, err = LoadMessageCatalogFile(filepath.Join(s.T().TempDir(), "missing.json"))
+	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), catalog)
+}
+
+func (s *MessageCatalogTestSuite) TestMessageFor() {
+	catalog := NewMessageCatalog()
+
+	message, err := catalog.MessageFor(MessageDenied, "es", &ResponseTemplateData{})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "acceso denegado", message)
+
+	message, err = catalog.MessageFor(MessageDenied, "fr", &ResponseTemplateData{})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "access denied", message)
+
+	_, err = catalog.MessageFor("unknown", "en", &ResponseTemplateData{})
+	assert.NotNil(s.T(), err)
+}
+
+func (s *MessageCatalogTestSuite) TestLoadFile_Kinds() {
+	path := filepath.Join(s.T().TempDir(), "messages.json")
+	os.WriteFile(path, []byte(`{"fr": {"blocked": "trop de requêtes", "denied": "accès refusé"}, "de": "zu viele Anfragen"}`), 0644)
+
+	catalog, err := LoadMessageCatalogFile(path)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "fr", catalog.LanguageFor(MessageDenied, "fr-CA"))
+	assert.Equal(s.T(), "en", catalog.LanguageFor(MessageDenied, "de"))
+
+	message, err := catalog.MessageFor(MessageDenied, "fr", &ResponseTemplateData{})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "accès refusé", message)
+
+	message, err = catalog.Message("de", &ResponseTemplateData{})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "zu viele Anfragen", message)
+}
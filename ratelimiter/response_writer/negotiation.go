@@ -0,0 +1,112 @@
+package response_writer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RateLimiterContentNegotiator picks a response writer from the Accept header of the request.
+type RateLimiterContentNegotiator struct {
+	mediaTypes []string
+	writers    map[string]RateLimiterResponseWriter
+}
+
+type acceptedMediaType struct {
+	mediaType string
+	quality   float64
+}
+
+func NewRateLimiterContentNegotiator(templates *ResponseTemplates) (*RateLimiterContentNegotiator, error) {
+	return NewRateLimiterContentNegotiatorWithCatalog(templates, NewMessageCatalog())
+}
+
+// NewRateLimiterContentNegotiatorWithCatalog localizes the detail of the blocked responses with catalog.
+func NewRateLimiterContentNegotiatorWithCatalog(templates *ResponseTemplates, catalog *MessageCatalog) (*RateLimiterContentNegotiator, error) {
+	problemJSON, err := newRateLimiterFormatResponseWriter(ContentTypeProblemJSON, templates, catalog, renderProblemJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonWriter, err := newRateLimiterFormatResponseWriter(ContentTypeJSON, templates, catalog, renderJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := newRateLimiterFormatResponseWriter(ContentTypeText, templates, catalog, renderText)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := newRateLimiterFormatResponseWriter(ContentTypeHTML, templates, catalog, renderHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimiterContentNegotiator{
+		mediaTypes: []string{ContentTypeProblemJSON, ContentTypeJSON, ContentTypeText, ContentTypeHTML},
+		writers: map[string]RateLimiterResponseWriter{
+			ContentTypeProblemJSON: problemJSON,
+			ContentTypeJSON:        jsonWriter,
+			ContentTypeText:        text,
+			ContentTypeHTML:        html,
+		},
+	}, nil
+}
+
+// Select returns nil when the Accept header is empty or accepts anything, so the configured writer is used.
+func (n *RateLimiterContentNegotiator) Select(accept string) RateLimiterResponseWriter {
+	for _, accepted := range parseAccept(accept) {
+		if accepted.mediaType == "*/*" {
+			return nil
+		}
+
+		if writer, ok := n.writers[accepted.mediaType]; ok {
+			return writer
+		}
+
+		if strings.HasSuffix(accepted.mediaType, "/*") {
+			prefix := strings.TrimSuffix(accepted.mediaType, "*")
+			for _, mediaType := range n.mediaTypes {
+				if strings.HasPrefix(mediaType, prefix) {
+					return n.writers[mediaType]
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseAccept(accept string) []*acceptedMediaType {
+	accepted := []*acceptedMediaType{}
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.EqualFold(name, "q") {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err == nil {
+					quality = parsed
+				}
+			}
+		}
+
+		if quality > 0 {
+			accepted = append(accepted, &acceptedMediaType{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+
+	return accepted
+}
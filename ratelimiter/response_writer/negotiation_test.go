@@ -0,0 +1,48 @@
+package response_writer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimiterContentNegotiatorTestSuite struct {
+	suite.Suite
+	negotiator *RateLimiterContentNegotiator
+}
+
+func TestRateLimiterContentNegotiatorTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimiterContentNegotiatorTestSuite))
+}
+
+func (s *RateLimiterContentNegotiatorTestSuite) SetupTest() {
+	negotiator, err := NewRateLimiterContentNegotiator(nil)
+	assert.Nil(s.T(), err)
+	s.negotiator = negotiator
+}
+
+func (s *RateLimiterContentNegotiatorTestSuite) TestSelect() {
+	expected := map[string]string{
+		"application/problem+json":                  ContentTypeProblemJSON,
+		"application/json":                          ContentTypeJSON,
+		"text/plain":                                ContentTypeText,
+		"text/html,application/xhtml+xml,*/*;q=0.8": ContentTypeHTML,
+		"application/json;q=0.5, text/plain":        ContentTypeText,
+		"application/*":                             ContentTypeProblemJSON,
+		"text/*":                                    ContentTypeText,
+		"image/png, TEXT/HTML;q=0.1":                ContentTypeHTML,
+	}
+
+	for accept, contentType := range expected {
+		responseWriter := s.negotiator.Select(accept)
+		assert.NotNil(s.T(), responseWriter, accept)
+		assert.Equal(s.T(), contentType, responseWriter.(*rateLimiterFormatResponseWriter).contentType, accept)
+	}
+}
+
+func (s *RateLimiterContentNegotiatorTestSuite) TestSelect_Fallback() {
+	for _, accept := range []string{"", "*/*", "image/png", "application/json;q=0", "*/*, application/json;q=0.5"} {
+		assert.Nil(s.T(), s.negotiator.Select(accept), accept)
+	}
+}
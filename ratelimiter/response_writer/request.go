@@ -0,0 +1,63 @@
+package response_writer
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RateLimiterRequestResponseWriter receives the request and the decision, unlike RateLimiterResponseWriter, and answers
+// the requests the access lists deny and the unknown tokens the policy rejects.
+type RateLimiterRequestResponseWriter interface {
+	WriteBlockedResponse(w http.ResponseWriter, r *http.Request, decision *RateLimitDecision) error
+	WriteErrorResponse(w http.ResponseWriter, r *http.Request, err *RateLimitError) error
+	WriteDeniedResponse(w http.ResponseWriter, r *http.Request) error
+	WriteUnauthorizedResponse(w http.ResponseWriter, r *http.Request) error
+}
+
+type RateLimitError struct {
+	KeyType  string
+	Key      string
+	RuleName string
+	Err      error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limiter check of %s \"%s\" failed: %s", e.KeyType, e.Key, e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+type rateLimiterResponseWriterAdapter struct {
+	responseWriter RateLimiterResponseWriter
+}
+
+// NewRateLimiterRequestResponseWriter returns responseWriter itself when it already implements RateLimiterRequestResponseWriter.
+func NewRateLimiterRequestResponseWriter(responseWriter RateLimiterResponseWriter) RateLimiterRequestResponseWriter {
+	if requestResponseWriter, ok := responseWriter.(RateLimiterRequestResponseWriter); ok {
+		return requestResponseWriter
+	}
+	return &rateLimiterResponseWriterAdapter{responseWriter: responseWriter}
+}
+
+func (rw *rateLimiterResponseWriterAdapter) WriteBlockedResponse(w http.ResponseWriter, r *http.Request, decision *RateLimitDecision) error {
+	return rw.responseWriter.WriteResponse(&w)
+}
+
+func (rw *rateLimiterResponseWriterAdapter) WriteErrorResponse(w http.ResponseWriter, r *http.Request, err *RateLimitError) error {
+	return rw.responseWriter.WriteError(&w, err)
+}
+
+func (rw *rateLimiterResponseWriterAdapter) WriteDeniedResponse(w http.ResponseWriter, r *http.Request) error {
+	return WriteDefaultResponse(w, r, MessageDenied)
+}
+
+func (rw *rateLimiterResponseWriterAdapter) WriteUnauthorizedResponse(w http.ResponseWriter, r *http.Request) error {
+	return WriteDefaultResponse(w, r, MessageUnauthorized)
+}
+
+// WriteDefaultResponse answers with the built-in message of a kind, in the language of the request.
+func WriteDefaultResponse(w http.ResponseWriter, r *http.Request, kind string) error {
+	return writeCatalogResponse(w, r, defaultMessageCatalog, kind, &ResponseTemplateData{})
+}
@@ -0,0 +1,144 @@
+package response_writer
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimiterRequestResponseWriterTestSuite struct {
+	suite.Suite
+}
+
+func TestRateLimiterRequestResponseWriterTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimiterRequestResponseWriterTestSuite))
+}
+
+type legacyResponseWriter struct {
+	err error
+}
+
+func (rw *legacyResponseWriter) WriteResponse(w *http.ResponseWriter) error {
+	(*w).WriteHeader(429)
+	(*w).Write([]byte("legacy"))
+	return nil
+}
+
+func (rw *legacyResponseWriter) WriteError(w *http.ResponseWriter, err error) error {
+	rw.err = err
+	(*w).WriteHeader(500)
+	return nil
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestAdapter_WriteBlockedResponse() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/path", nil)
+
+	responseWriter := NewRateLimiterRequestResponseWriter(&legacyResponseWriter{})
+	err := responseWriter.WriteBlockedResponse(recorder, request, &RateLimitDecision{})
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 429, response.StatusCode)
+	assert.Equal(s.T(), "legacy", string(responseBody))
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestAdapter_WriteErrorResponse() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/path", nil)
+	storageErr := errors.New("connection refused")
+
+	legacy := &legacyResponseWriter{}
+	responseWriter := NewRateLimiterRequestResponseWriter(legacy)
+	err := responseWriter.WriteErrorResponse(recorder, request, &RateLimitError{KeyType: "IP", Key: "127.0.0.1", RuleName: "ip", Err: storageErr})
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 500, recorder.Result().StatusCode)
+	assert.ErrorIs(s.T(), legacy.err, storageErr)
+	assert.Equal(s.T(), "rate limiter check of IP \"127.0.0.1\" failed: connection refused", legacy.err.Error())
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestAdapter_WriteDeniedResponse() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/path", nil)
+
+	responseWriter := NewRateLimiterRequestResponseWriter(&legacyResponseWriter{})
+	err := responseWriter.WriteDeniedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 403, response.StatusCode)
+	assert.Equal(s.T(), "access denied", string(responseBody))
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestAdapter_WriteDeniedResponse_Localized() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/path", nil)
+	request.Header.Set("Accept-Language", "pt-BR")
+
+	responseWriter := NewRateLimiterRequestResponseWriter(&legacyResponseWriter{})
+	err := responseWriter.WriteDeniedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 403, response.StatusCode)
+	assert.Equal(s.T(), "pt-BR", response.Header.Get("Content-Language"))
+	assert.Equal(s.T(), "acesso negado", string(responseBody))
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestAdapter_WriteUnauthorizedResponse() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/path", nil)
+
+	responseWriter := NewRateLimiterRequestResponseWriter(&legacyResponseWriter{})
+	err := responseWriter.WriteUnauthorizedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 401, response.StatusCode)
+	assert.Equal(s.T(), "unknown API token", string(responseBody))
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestAdapter_WriteUnauthorizedResponse_Localized() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/path", nil)
+	request.Header.Set("Accept-Language", "es")
+
+	responseWriter := NewRateLimiterRequestResponseWriter(&legacyResponseWriter{})
+	err := responseWriter.WriteUnauthorizedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 401, response.StatusCode)
+	assert.Equal(s.T(), "es", response.Header.Get("Content-Language"))
+	assert.Equal(s.T(), "token de API desconocido", string(responseBody))
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestNewRateLimiterRequestResponseWriter_Native() {
+	responseWriter, err := NewRateLimiterProblemJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), responseWriter, NewRateLimiterRequestResponseWriter(responseWriter))
+}
+
+func (s *RateLimiterRequestResponseWriterTestSuite) TestProblemJSON_Instance() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing/orders", nil)
+
+	responseWriter, err := NewRateLimiterProblemJSONResponseWriter(nil)
+	assert.Nil(s.T(), err)
+	err = responseWriter.WriteBlockedResponse(recorder, request, &RateLimitDecision{})
+
+	responseBody, _ := ioutil.ReadAll(recorder.Result().Body)
+	assert.Nil(s.T(), err)
+	assert.Contains(s.T(), string(responseBody), `"instance":"/orders"`)
+}
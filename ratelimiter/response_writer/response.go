@@ -10,12 +10,18 @@ type RateLimiterResponseWriter interface {
 type rateLimiterDefaultResponseWriter struct {
 	statusCode int
 	message    string
+	catalog    *MessageCatalog
 }
 
 func NewRateLimiterDefaultResponseWriter() *rateLimiterDefaultResponseWriter {
+	return NewRateLimiterDefaultResponseWriterWithCatalog(NewMessageCatalog())
+}
+
+func NewRateLimiterDefaultResponseWriterWithCatalog(catalog *MessageCatalog) *rateLimiterDefaultResponseWriter {
 	responseWriter := &rateLimiterDefaultResponseWriter{}
 	responseWriter.statusCode = 429
 	responseWriter.message = "you have reached the maximum number of requests or actions allowed within a certain time frame"
+	responseWriter.catalog = catalog
 	return responseWriter
 }
 
@@ -30,3 +36,24 @@ func (rw *rateLimiterDefaultResponseWriter) WriteError(w *http.ResponseWriter, e
 	(*w).Write([]byte("internal server error"))
 	return nil
 }
+
+func (rw *rateLimiterDefaultResponseWriter) WriteBlockedResponse(w http.ResponseWriter, r *http.Request, decision *RateLimitDecision) error {
+	return writeCatalogResponse(w, r, rw.catalog, MessageBlocked, &ResponseTemplateData{
+		RetryAfter: ceilSeconds(decision.RetryAfter),
+		Limit:      decision.Limit,
+		Remaining:  decision.Remaining,
+		ResetAt:    decision.ResetAt,
+	})
+}
+
+func (rw *rateLimiterDefaultResponseWriter) WriteErrorResponse(w http.ResponseWriter, r *http.Request, err *RateLimitError) error {
+	return rw.WriteError(&w, err)
+}
+
+func (rw *rateLimiterDefaultResponseWriter) WriteDeniedResponse(w http.ResponseWriter, r *http.Request) error {
+	return writeCatalogResponse(w, r, rw.catalog, MessageDenied, &ResponseTemplateData{})
+}
+
+func (rw *rateLimiterDefaultResponseWriter) WriteUnauthorizedResponse(w http.ResponseWriter, r *http.Request) error {
+	return writeCatalogResponse(w, r, rw.catalog, MessageUnauthorized, &ResponseTemplateData{})
+}
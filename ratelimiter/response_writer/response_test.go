@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -51,3 +52,71 @@ func (s *NewRateLimiterDefaultResponseWriterTestSuite) TestWriteError() {
 	assert.Equal(s.T(), 500, responseStatus)
 	assert.Equal(s.T(), "internal server error", string(responseBody))
 }
+
+func (s *NewRateLimiterDefaultResponseWriterTestSuite) TestWriteBlockedResponse_Localized() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("Accept-Language", "pt-BR,pt;q=0.9,en;q=0.8")
+
+	responseWriter := NewRateLimiterDefaultResponseWriter()
+	err := responseWriter.WriteBlockedResponse(recorder, request, &RateLimitDecision{Limit: 10, RetryAfter: time.Millisecond * 2500})
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 429, response.StatusCode)
+	assert.Equal(s.T(), "pt-BR", response.Header.Get("Content-Language"))
+	assert.Equal(s.T(), "você atingiu o limite de 10 requisições permitidas em um determinado período, tente novamente em 3 segundos", string(responseBody))
+}
+
+func (s *NewRateLimiterDefaultResponseWriterTestSuite) TestWriteBlockedResponse_DefaultLanguage() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing", nil)
+
+	responseWriter := NewRateLimiterDefaultResponseWriter()
+	err := responseWriter.WriteBlockedResponse(recorder, request, &RateLimitDecision{Limit: 5, RetryAfter: time.Second})
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "en", response.Header.Get("Content-Language"))
+	assert.Equal(s.T(), "you have reached the limit of 5 requests allowed within a certain time frame, try again in 1 seconds", string(responseBody))
+}
+
+func (s *NewRateLimiterDefaultResponseWriterTestSuite) TestWriteDeniedResponse_Catalog() {
+	catalog := NewMessageCatalog()
+	assert.Nil(s.T(), catalog.AddMessage(MessageDenied, "fr", "accès refusé"))
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("Accept-Language", "fr")
+
+	responseWriter := NewRateLimiterDefaultResponseWriterWithCatalog(catalog)
+	err := responseWriter.WriteDeniedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 403, response.StatusCode)
+	assert.Equal(s.T(), "fr", response.Header.Get("Content-Language"))
+	assert.Equal(s.T(), "accès refusé", string(responseBody))
+}
+
+func (s *NewRateLimiterDefaultResponseWriterTestSuite) TestWriteUnauthorizedResponse_Localized() {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("Accept-Language", "pt-BR")
+
+	responseWriter := NewRateLimiterDefaultResponseWriter()
+	err := responseWriter.WriteUnauthorizedResponse(recorder, request)
+
+	response := recorder.Result()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 401, response.StatusCode)
+	assert.Equal(s.T(), "token de API desconhecido", string(responseBody))
+}
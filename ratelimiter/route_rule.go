@@ -0,0 +1,173 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RouteRule applies its own limits to the requests matching Path and Methods. Path accepts chi-style parameters,
+// like "/users/{id}" or "/users/{id:[0-9]+}", a trailing "/*" for any non-empty suffix, and globs inside a segment, like "/v*/login".
+// An empty Methods matches every method and an empty KeyType uses the key the request would get without the rule.
+type RouteRule struct {
+	Name     string      `json:"name"`
+	Path     string      `json:"path"`
+	Methods  []string    `json:"methods"`
+	KeyType  string      `json:"keyType"`
+	Rate     *RateConfig `json:"rate"`
+	segments []*routeSegment
+}
+
+type routeSegment struct {
+	value    string
+	param    bool
+	pattern  *regexp.Regexp
+	wildcard bool
+}
+
+// LoadRouteRulesFile reads a list of rules, like [{"path": "/login", "methods": ["POST"], "rate": {...}}], from a YAML
+// file, when the extension is .yaml or .yml, or from a JSON file.
+func LoadRouteRulesFile(path string) ([]*RouteRule, error) {
+	content, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []*RouteRule{}
+	err = json.Unmarshal(content, &rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// GetName returns Name or, when it is empty, the methods and path of the rule. It also scopes the counters of the rule.
+func (r *RouteRule) GetName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if len(r.Methods) == 0 {
+		return r.Path
+	}
+	return strings.ToUpper(strings.Join(r.Methods, ",")) + " " + r.Path
+}
+
+// GetScope keeps the counters of the rule apart from the global ones and from the other rules.
+func (r *RouteRule) GetScope() string {
+	return "ROUTE-" + r.GetName()
+}
+
+func (r *RouteRule) Compile() error {
+	if r.Rate != nil {
+		if err := r.Rate.Validate(); err != nil {
+			return err
+		}
+	}
+
+	segments, err := compileRoute(r.Path)
+	if err != nil {
+		return err
+	}
+	r.segments = segments
+	return nil
+}
+
+func compileRoute(route string) ([]*routeSegment, error) {
+	segments := []*routeSegment{}
+	parts := splitPath(route)
+	for i, part := range parts {
+		segment := &routeSegment{value: part}
+
+		switch {
+		case part == "*" && i == len(parts)-1:
+			segment.wildcard = true
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			segment.param = true
+			_, expression, found := strings.Cut(part[1:len(part)-1], ":")
+			if found {
+				pattern, err := regexp.Compile("^(?:" + expression + ")$")
+				if err != nil {
+					return nil, fmt.Errorf("invalid route \"%s\": %w", route, err)
+				}
+				segment.pattern = pattern
+			}
+		default:
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, fmt.Errorf("invalid route \"%s\": %w", route, err)
+			}
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
+
+func (r *RouteRule) Matches(method string, requestPath string) bool {
+	if len(r.Methods) > 0 && !containsFold(r.Methods, method) {
+		return false
+	}
+
+	segments := r.segments
+	if segments == nil {
+		var err error
+		segments, err = compileRoute(r.Path)
+		if err != nil {
+			return false
+		}
+	}
+
+	return matchRoute(segments, requestPath)
+}
+
+func matchRoute(segments []*routeSegment, requestPath string) bool {
+	parts := splitPath(requestPath)
+	for i, segment := range segments {
+		if segment.wildcard {
+			return i < len(parts)
+		}
+		if i >= len(parts) || !segment.matches(parts[i]) {
+			return false
+		}
+	}
+
+	return len(parts) == len(segments)
+}
+
+func (s *routeSegment) matches(part string) bool {
+	if s.param {
+		return part != "" && (s.pattern == nil || s.pattern.MatchString(part))
+	}
+	matched, _ := path.Match(s.value, part)
+	return matched
+}
+
+// GetRouteRule returns the first rule in Routes that matches the request, or nil.
+func (c *LimiterConfig) GetRouteRule(method string, requestPath string) *RouteRule {
+	for _, rule := range c.Routes {
+		if rule.Matches(method, requestPath) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func splitPath(value string) []string {
+	value = strings.Trim(value, "/")
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, "/")
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package ratelimiter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RouteRuleTestSuite struct {
+	suite.Suite
+}
+
+func TestRouteRuleTestSuite(t *testing.T) {
+	suite.Run(t, new(RouteRuleTestSuite))
+}
+
+func (s *RouteRuleTestSuite) TestMatches() {
+	rule := &RouteRule{Path: "/users/{id:[0-9]+}/orders/{order}", Methods: []string{"GET", "post"}}
+
+	assert.Nil(s.T(), rule.Compile())
+	assert.True(s.T(), rule.Matches("GET", "/users/42/orders/abc"))
+	assert.True(s.T(), rule.Matches("POST", "/users/42/orders/abc/"))
+	assert.False(s.T(), rule.Matches("DELETE", "/users/42/orders/abc"))
+	assert.False(s.T(), rule.Matches("GET", "/users/john/orders/abc"))
+	assert.False(s.T(), rule.Matches("GET", "/users/42/orders"))
+	assert.False(s.T(), rule.Matches("GET", "/users/42/orders/abc/items"))
+}
+
+func (s *RouteRuleTestSuite) TestMatches_WildcardAndGlob() {
+	wildcard := &RouteRule{Path: "/admin/*"}
+	assert.True(s.T(), wildcard.Matches("GET", "/admin/users"))
+	assert.True(s.T(), wildcard.Matches("DELETE", "/admin/users/42"))
+	assert.False(s.T(), wildcard.Matches("GET", "/users"))
+	assert.False(s.T(), wildcard.Matches("GET", "/admin"))
+	assert.False(s.T(), wildcard.Matches("GET", "/admin/"))
+
+	glob := &RouteRule{Path: "/v*/login", Methods: []string{"POST"}}
+	assert.True(s.T(), glob.Matches("POST", "/v1/login"))
+	assert.True(s.T(), glob.Matches("POST", "/v2/login"))
+	assert.False(s.T(), glob.Matches("POST", "/api/login"))
+
+	root := &RouteRule{Path: "/"}
+	assert.True(s.T(), root.Matches("GET", "/"))
+	assert.False(s.T(), root.Matches("GET", "/login"))
+}
+
+func (s *RouteRuleTestSuite) TestCompile_Invalid() {
+	assert.NotNil(s.T(), (&RouteRule{Path: "/users/{id:[0-9}"}).Compile())
+	assert.NotNil(s.T(), (&RouteRule{Path: "/files/[a-"}).Compile())
+	assert.False(s.T(), (&RouteRule{Path: "/users/{id:[0-9}"}).Matches("GET", "/users/1"))
+}
+
+func (s *RouteRuleTestSuite) TestGetName() {
+	assert.Equal(s.T(), "login", (&RouteRule{Name: "login", Path: "/login"}).GetName())
+	assert.Equal(s.T(), "/login", (&RouteRule{Path: "/login"}).GetName())
+	assert.Equal(s.T(), "GET,POST /login", (&RouteRule{Path: "/login", Methods: []string{"get", "post"}}).GetName())
+}
+
+func (s *RouteRuleTestSuite) TestGetRouteRule() {
+	login := &RouteRule{Path: "/login", Methods: []string{"POST"}}
+	users := &RouteRule{Path: "/users/*"}
+	config := &LimiterConfig{Routes: []*RouteRule{login, users, {Path: "/users/{id}"}}}
+
+	assert.Equal(s.T(), login, config.GetRouteRule("POST", "/login"))
+	assert.Nil(s.T(), config.GetRouteRule("GET", "/login"))
+	assert.Equal(s.T(), users, config.GetRouteRule("GET", "/users/42"))
+	assert.Nil(s.T(), (&LimiterConfig{}).GetRouteRule("GET", "/users/42"))
+}
+
+func (s *RouteRuleTestSuite) TestLoadRouteRulesFile() {
+	path := filepath.Join(s.T().TempDir(), "routes.json")
+	os.WriteFile(path, []byte(`[{"name": "login", "path": "/login", "methods": ["POST"], "keyType": "IP", "rate": {"maxRequestsPerSecond": 5}}]`), 0644)
+
+	rules, err := LoadRouteRulesFile(path)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), rules, 1)
+	assert.Equal(s.T(), "login", rules[0].Name)
+	assert.Equal(s.T(), []string{"POST"}, rules[0].Methods)
+	assert.Equal(s.T(), "IP", rules[0].KeyType)
+	assert.Equal(s.T(), int64(5), rules[0].Rate.MaxRequestsPerSecond)
+
+	yamlPath := filepath.Join(s.T().TempDir(), "routes.yaml")
+	os.WriteFile(yamlPath, []byte(`
+- name: login
+  path: /login
+  methods: [POST]
+  rate:
+    maxRequestsPerSecond: 5
+    blockTimeMilliseconds: 60000
+`), 0644)
+
+	rules, err = LoadRouteRulesFile(yamlPath)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), rules, 1)
+	assert.Equal(s.T(), "/login", rules[0].Path)
+	assert.Equal(s.T(), []string{"POST"}, rules[0].Methods)
+	assert.Equal(s.T(), int64(60000), rules[0].Rate.BlockTimeMilliseconds)
+
+	_, err = LoadRouteRulesFile(filepath.Join(s.T().TempDir(), "missing.json"))
+	assert.NotNil(s.T(), err)
+}
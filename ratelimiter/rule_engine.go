@@ -0,0 +1,272 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const RuleModeFirstMatch = "first_match"
+const RuleModeAllMatch = "all_match"
+
+const keyTypeRule = "RULE"
+
+// Rule applies its limits to the requests matching every condition of Match, counting them by the key built from the Key
+// template. An empty Key uses the key the request would get without the rule.
+type Rule struct {
+	Name     string      `json:"name"`
+	Match    RuleMatch   `json:"match"`
+	Key      string      `json:"key"`
+	Rate     *RateConfig `json:"rate"`
+	compiled *compiledRule
+}
+
+// RuleMatch holds the conditions of a Rule. Host, UserAgent and the values of Headers and Query are globs where "*"
+// matches any text, so an empty value matches a missing header or parameter. Empty conditions match every request.
+type RuleMatch struct {
+	Path       string            `json:"path"`
+	Methods    []string          `json:"methods"`
+	Host       string            `json:"host"`
+	UserAgent  string            `json:"userAgent"`
+	Headers    map[string]string `json:"headers"`
+	Query      map[string]string `json:"query"`
+	TokenPlans []string          `json:"tokenPlans"`
+}
+
+// RuleSet is the content of a rules file.
+type RuleSet struct {
+	Mode  string  `json:"mode"`
+	Rules []*Rule `json:"rules"`
+}
+
+// RuleKeyData is available to the Key template of a Rule, like "{{.Header \"X-Tenant\"}}:{{.IP}}".
+type RuleKeyData struct {
+	IP        string
+	Token     string
+	Plan      string
+	Method    string
+	Path      string
+	Host      string
+	UserAgent string
+	request   *http.Request
+}
+
+type compiledRule struct {
+	path      []*routeSegment
+	host      *regexp.Regexp
+	userAgent *regexp.Regexp
+	headers   map[string]*regexp.Regexp
+	query     map[string]*regexp.Regexp
+	key       *template.Template
+}
+
+// LoadRulesFile reads a RuleSet from a YAML file, when the extension is .yaml or .yml, or from a JSON file.
+func LoadRulesFile(path string) (*RuleSet, error) {
+	content, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSet := &RuleSet{}
+	err = json.Unmarshal(content, ruleSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleSet, nil
+}
+
+func (c *LimiterConfig) GetRuleMode() string {
+	if c.RuleMode == "" {
+		return RuleModeFirstMatch
+	}
+	return c.RuleMode
+}
+
+func (c *LimiterConfig) GetTokenPlan(token string) string {
+	if token == "" || c.TokenPlanProvider == nil {
+		return ""
+	}
+	return c.TokenPlanProvider.GetTokenPlan(token)
+}
+
+// MatchRules returns the first rule in Rules that matches the request or, under RuleModeAllMatch, every one that does.
+func (c *LimiterConfig) MatchRules(r *http.Request) []*Rule {
+	rules := []*Rule{}
+	for _, rule := range c.Rules {
+		if !rule.Matches(r, c) {
+			continue
+		}
+		rules = append(rules, rule)
+		if c.GetRuleMode() != RuleModeAllMatch {
+			break
+		}
+	}
+	return rules
+}
+
+func (r *Rule) Compile() error {
+	compiled, err := r.compile()
+	if err != nil {
+		return err
+	}
+	r.compiled = compiled
+	return nil
+}
+
+func (r *Rule) compile() (*compiledRule, error) {
+	if r.Name == "" {
+		return nil, errors.New("rule without name")
+	}
+	if r.Rate == nil {
+		return nil, fmt.Errorf("rule \"%s\" without rate", r.Name)
+	}
+	if err := r.Rate.Validate(); err != nil {
+		return nil, err
+	}
+
+	compiled := &compiledRule{headers: map[string]*regexp.Regexp{}, query: map[string]*regexp.Regexp{}}
+
+	if r.Match.Host != "" {
+		compiled.host = compileGlob(r.Match.Host, true)
+	}
+	if r.Match.UserAgent != "" {
+		compiled.userAgent = compileGlob(r.Match.UserAgent, false)
+	}
+	if r.Match.Path != "" {
+		segments, err := compileRoute(r.Match.Path)
+		if err != nil {
+			return nil, err
+		}
+		compiled.path = segments
+	}
+
+	for name, value := range r.Match.Headers {
+		compiled.headers[name] = compileGlob(value, false)
+	}
+	for name, value := range r.Match.Query {
+		compiled.query[name] = compileGlob(value, false)
+	}
+
+	if r.Key != "" {
+		key, err := template.New(r.Name).Option("missingkey=error").Parse(r.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key of rule \"%s\": %w", r.Name, err)
+		}
+		compiled.key = key
+	}
+
+	return compiled, nil
+}
+
+func (r *Rule) getCompiled() *compiledRule {
+	if r.compiled != nil {
+		return r.compiled
+	}
+	compiled, _ := r.compile()
+	return compiled
+}
+
+func (r *Rule) Matches(request *http.Request, config *LimiterConfig) bool {
+	compiled := r.getCompiled()
+	if compiled == nil {
+		return false
+	}
+
+	match := &r.Match
+	if len(match.Methods) > 0 && !containsFold(match.Methods, request.Method) {
+		return false
+	}
+	if compiled.path != nil && !matchRoute(compiled.path, request.URL.Path) {
+		return false
+	}
+	if compiled.host != nil && !compiled.host.MatchString(request.Host) {
+		return false
+	}
+	if compiled.userAgent != nil && !compiled.userAgent.MatchString(request.UserAgent()) {
+		return false
+	}
+	for name, value := range compiled.headers {
+		if !value.MatchString(request.Header.Get(name)) {
+			return false
+		}
+	}
+	for name, value := range compiled.query {
+		if !value.MatchString(request.URL.Query().Get(name)) {
+			return false
+		}
+	}
+	if len(match.TokenPlans) > 0 && !containsFold(match.TokenPlans, config.GetTokenPlan(config.GetToken(request))) {
+		return false
+	}
+
+	return true
+}
+
+// NewCheck returns the check of a rule with a Key template, or nil when the template gives an empty key.
+func (r *Rule) NewCheck(request *http.Request, config *LimiterConfig) *RateLimitCheck {
+	compiled := r.getCompiled()
+	if compiled == nil || compiled.key == nil {
+		return nil
+	}
+
+	key := bytes.Buffer{}
+	err := compiled.key.Execute(&key, r.newKeyData(request, config))
+	if err != nil {
+		PrintfWD(config, "could not build the key of rule \"%s\": %s", r.Name, err)
+		return nil
+	}
+	if key.Len() == 0 {
+		return nil
+	}
+
+	return &RateLimitCheck{KeyType: keyTypeRule, Key: key.String(), RuleName: r.GetRuleName(), RateConfig: r.Rate, Scope: r.GetScope()}
+}
+
+func (r *Rule) GetRuleName() string {
+	return "rules." + r.Name
+}
+
+// GetScope keeps the counters of the rule apart from the global ones and from the other rules.
+func (r *Rule) GetScope() string {
+	return "RULE-" + r.Name
+}
+
+func (r *Rule) newKeyData(request *http.Request, config *LimiterConfig) *RuleKeyData {
+	token := config.GetToken(request)
+	if config.IsUnknownToken(token) {
+		token = ""
+	}
+
+	return &RuleKeyData{
+		IP:        r.Rate.NormalizeIP(config.GetClientIP(request)),
+		Token:     token,
+		Plan:      config.GetTokenPlan(token),
+		Method:    request.Method,
+		Path:      request.URL.Path,
+		Host:      request.Host,
+		UserAgent: request.UserAgent(),
+		request:   request,
+	}
+}
+
+func (d *RuleKeyData) Header(name string) string {
+	return d.request.Header.Get(name)
+}
+
+func (d *RuleKeyData) Query(name string) string {
+	return d.request.URL.Query().Get(name)
+}
+
+func compileGlob(pattern string, ignoreCase bool) *regexp.Regexp {
+	expression := strings.ReplaceAll(strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*"), `\?`, ".")
+	if ignoreCase {
+		expression = "(?i)" + expression
+	}
+	return regexp.MustCompile("^" + expression + "$")
+}
@@ -0,0 +1,166 @@
+package ratelimiter
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RuleEngineTestSuite struct {
+	suite.Suite
+	config *LimiterConfig
+}
+
+func TestRuleEngineTestSuite(t *testing.T) {
+	suite.Run(t, new(RuleEngineTestSuite))
+}
+
+func (s *RuleEngineTestSuite) SetupTest() {
+	s.config = &LimiterConfig{
+		IP:           &RateConfig{MaxRequestsPerSecond: 10},
+		Token:        &RateConfig{MaxRequestsPerSecond: 20},
+		CustomTokens: &map[string]*RateConfig{},
+		TokenPlanProvider: TokenPlanProviderFunc(func(token string) string {
+			if token == "abc" {
+				return "pro"
+			}
+			return "free"
+		}),
+	}
+}
+
+func (s *RuleEngineTestSuite) TestMatches() {
+	mobile := &Rule{Name: "mobile", Rate: &RateConfig{MaxRequestsPerSecond: 50}, Match: RuleMatch{
+		Path:      "/v2/*",
+		Methods:   []string{"GET"},
+		Host:      "API.example.com",
+		UserAgent: "*Android*",
+		Query:     map[string]string{"debug": ""},
+	}}
+	assert.Nil(s.T(), mobile.Compile())
+
+	request := httptest.NewRequest("GET", "http://api.example.com/v2/orders/42", nil)
+	request.Header.Set("User-Agent", "okhttp/4.9 (Linux; Android 14)")
+	assert.True(s.T(), mobile.Matches(request, s.config))
+
+	request = httptest.NewRequest("GET", "http://api.example.com/v2/orders?debug=1", nil)
+	request.Header.Set("User-Agent", "okhttp/4.9 (Linux; Android 14)")
+	assert.False(s.T(), mobile.Matches(request, s.config))
+
+	request = httptest.NewRequest("GET", "http://api.example.com/v1/orders", nil)
+	request.Header.Set("User-Agent", "okhttp/4.9 (Linux; Android 14)")
+	assert.False(s.T(), mobile.Matches(request, s.config))
+
+	request = httptest.NewRequest("GET", "http://api.example.com/v2/orders", nil)
+	request.Header.Set("User-Agent", "Mozilla/5.0")
+	assert.False(s.T(), mobile.Matches(request, s.config))
+
+	request = httptest.NewRequest("POST", "http://other.example.com/v2/orders", nil)
+	request.Header.Set("User-Agent", "okhttp/4.9 (Linux; Android 14)")
+	assert.False(s.T(), mobile.Matches(request, s.config))
+}
+
+func (s *RuleEngineTestSuite) TestMatches_MissingHeaderAndTokenPlan() {
+	withoutUserAgent := &Rule{Name: "no-user-agent", Rate: &RateConfig{MaxRequestsPerSecond: 1}, Match: RuleMatch{
+		Headers: map[string]string{"User-Agent": ""},
+	}}
+	pro := &Rule{Name: "pro", Rate: &RateConfig{MaxRequestsPerSecond: 500}, Match: RuleMatch{TokenPlans: []string{"pro"}}}
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	assert.True(s.T(), withoutUserAgent.Matches(request, s.config))
+	assert.False(s.T(), pro.Matches(request, s.config))
+
+	request.Header.Set("User-Agent", "curl/8.0")
+	request.Header.Set("API_KEY", "abc")
+	assert.False(s.T(), withoutUserAgent.Matches(request, s.config))
+	assert.True(s.T(), pro.Matches(request, s.config))
+
+	request.Header.Set("API_KEY", "def")
+	assert.False(s.T(), pro.Matches(request, s.config))
+}
+
+func (s *RuleEngineTestSuite) TestMatchRules_Mode() {
+	first := &Rule{Name: "first", Rate: &RateConfig{MaxRequestsPerSecond: 1}, Match: RuleMatch{Path: "/login"}}
+	second := &Rule{Name: "second", Rate: &RateConfig{MaxRequestsPerSecond: 2}, Match: RuleMatch{Methods: []string{"POST"}}}
+	s.config.Rules = []*Rule{first, second}
+	request := httptest.NewRequest("POST", "http://testing/login", nil)
+
+	assert.Equal(s.T(), []*Rule{first}, s.config.MatchRules(request))
+
+	s.config.RuleMode = RuleModeAllMatch
+	assert.Equal(s.T(), []*Rule{first, second}, s.config.MatchRules(request))
+	assert.Equal(s.T(), []*Rule{second}, s.config.MatchRules(httptest.NewRequest("POST", "http://testing/orders", nil)))
+	assert.Empty(s.T(), s.config.MatchRules(httptest.NewRequest("GET", "http://testing/orders", nil)))
+}
+
+func (s *RuleEngineTestSuite) TestNewCheck() {
+	rule := &Rule{Name: "tenant", Key: `{{.Header "X-Tenant"}}:{{.IP}}:{{.Plan}}`, Rate: &RateConfig{MaxRequestsPerSecond: 5, IPv4PrefixLength: 24}}
+	assert.Nil(s.T(), rule.Compile())
+
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("X-Tenant", "acme")
+	request.Header.Set("API_KEY", "abc")
+
+	check := rule.NewCheck(request, s.config)
+	assert.Equal(s.T(), "RULE", check.KeyType)
+	assert.Equal(s.T(), "acme:192.0.2.0/24:pro", check.Key)
+	assert.Equal(s.T(), "rules.tenant", check.RuleName)
+	assert.Equal(s.T(), "RULE-tenant", check.Scope)
+	assert.Equal(s.T(), rule.Rate, check.RateConfig)
+
+	token := &Rule{Name: "token", Key: "{{.Token}}", Rate: &RateConfig{MaxRequestsPerSecond: 5}}
+	assert.Nil(s.T(), token.NewCheck(httptest.NewRequest("GET", "http://testing", nil), s.config))
+	assert.Nil(s.T(), (&Rule{Name: "default", Rate: &RateConfig{}}).NewCheck(request, s.config))
+}
+
+func (s *RuleEngineTestSuite) TestCompile_Invalid() {
+	assert.NotNil(s.T(), (&Rule{Rate: &RateConfig{}}).Compile())
+	assert.NotNil(s.T(), (&Rule{Name: "no-rate"}).Compile())
+	assert.NotNil(s.T(), (&Rule{Name: "path", Rate: &RateConfig{}, Match: RuleMatch{Path: "/users/{id:[0-9}"}}).Compile())
+	assert.NotNil(s.T(), (&Rule{Name: "key", Rate: &RateConfig{}, Key: "{{.IP"}).Compile())
+	assert.False(s.T(), (&Rule{Name: "no-rate"}).Matches(httptest.NewRequest("GET", "http://testing", nil), s.config))
+}
+
+func (s *RuleEngineTestSuite) TestLoadRulesFile() {
+	directory := s.T().TempDir()
+
+	jsonPath := filepath.Join(directory, "rules.json")
+	os.WriteFile(jsonPath, []byte(`{"mode": "all_match", "rules": [{"name": "mobile", "match": {"path": "/v2/*", "userAgent": "*Android*"}, "rate": {"maxRequestsPerSecond": 50}}]}`), 0644)
+
+	ruleSet, err := LoadRulesFile(jsonPath)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), RuleModeAllMatch, ruleSet.Mode)
+	assert.Len(s.T(), ruleSet.Rules, 1)
+	assert.Equal(s.T(), "/v2/*", ruleSet.Rules[0].Match.Path)
+	assert.Equal(s.T(), int64(50), ruleSet.Rules[0].Rate.MaxRequestsPerSecond)
+
+	yamlPath := filepath.Join(directory, "rules.yaml")
+	os.WriteFile(yamlPath, []byte(`
+rules:
+  - name: no-user-agent
+    match:
+      headers:
+        User-Agent: ""
+    key: "{{.IP}}"
+    rate:
+      maxRequestsPerSecond: 1
+      blockTimeMilliseconds: 1000
+`), 0644)
+
+	ruleSet, err = LoadRulesFile(yamlPath)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "", ruleSet.Mode)
+	assert.Len(s.T(), ruleSet.Rules, 1)
+	assert.Equal(s.T(), map[string]string{"User-Agent": ""}, ruleSet.Rules[0].Match.Headers)
+	assert.Equal(s.T(), "{{.IP}}", ruleSet.Rules[0].Key)
+	assert.Equal(s.T(), int64(1000), ruleSet.Rules[0].Rate.BlockTimeMilliseconds)
+
+	invalidPath := filepath.Join(directory, "invalid.yml")
+	os.WriteFile(invalidPath, []byte("rules: [\n"), 0644)
+	_, err = LoadRulesFile(invalidPath)
+	assert.NotNil(s.T(), err)
+}
@@ -0,0 +1,112 @@
+package ratelimiter
+
+import (
+	"net/http"
+	"strings"
+)
+
+const TokenSourceHeader = "header"
+const TokenSourceBearer = "bearer"
+const TokenSourceQuery = "query"
+const TokenSourceCookie = "cookie"
+
+const defaultTokenHeader = "API_KEY"
+
+const UnknownTokenPolicyAllow = "allow"
+const UnknownTokenPolicyIP = "ip"
+const UnknownTokenPolicyReject = "reject"
+
+type TokenSource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// TokenRegistry tells which tokens exist besides the ones in CustomTokens, e.g. by looking them up in a database.
+type TokenRegistry interface {
+	HasToken(token string) bool
+}
+
+// TokenRegistryFunc lets a plain function be used as a TokenRegistry.
+type TokenRegistryFunc func(token string) bool
+
+func (f TokenRegistryFunc) HasToken(token string) bool {
+	return f(token)
+}
+
+// TokenPlanProvider returns the plan of a token, like "free" or "pro", for the rules matching on RuleMatch.TokenPlans.
+type TokenPlanProvider interface {
+	GetTokenPlan(token string) string
+}
+
+// TokenPlanProviderFunc lets a plain function be used as a TokenPlanProvider.
+type TokenPlanProviderFunc func(token string) string
+
+func (f TokenPlanProviderFunc) GetTokenPlan(token string) string {
+	return f(token)
+}
+
+func (t *TokenSource) Extract(r *http.Request) string {
+	switch t.Type {
+	case TokenSourceHeader:
+		return r.Header.Get(t.Name)
+	case TokenSourceBearer:
+		name := t.Name
+		if name == "" {
+			name = "Authorization"
+		}
+		scheme, token, found := strings.Cut(r.Header.Get(name), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	case TokenSourceQuery:
+		return r.URL.Query().Get(t.Name)
+	case TokenSourceCookie:
+		cookie, err := r.Cookie(t.Name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	default:
+		return ""
+	}
+}
+
+func (c *LimiterConfig) GetTokenSources() []*TokenSource {
+	if len(c.TokenSources) > 0 {
+		return c.TokenSources
+	}
+	return []*TokenSource{{Type: TokenSourceHeader, Name: defaultTokenHeader}}
+}
+
+// GetToken returns the first non-empty token found in the configured sources, in order.
+func (c *LimiterConfig) GetToken(r *http.Request) string {
+	for _, source := range c.GetTokenSources() {
+		token := source.Extract(r)
+		if token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+func (c *LimiterConfig) GetUnknownTokenPolicy() string {
+	if c.UnknownTokenPolicy == "" {
+		return UnknownTokenPolicyAllow
+	}
+	return c.UnknownTokenPolicy
+}
+
+func (c *LimiterConfig) IsKnownToken(token string) bool {
+	if c.CustomTokens != nil {
+		if _, ok := (*c.CustomTokens)[token]; ok {
+			return true
+		}
+	}
+	return c.TokenRegistry != nil && c.TokenRegistry.HasToken(token)
+}
+
+// IsUnknownToken reports whether token must not get a token limit of its own under the unknown token policy.
+func (c *LimiterConfig) IsUnknownToken(token string) bool {
+	return c.GetUnknownTokenPolicy() != UnknownTokenPolicyAllow && !c.IsKnownToken(token)
+}
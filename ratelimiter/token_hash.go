@@ -0,0 +1,67 @@
+package ratelimiter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const keyTypeIP = "IP"
+
+// HashToken returns the HMAC-SHA256 of token keyed with TokenHashSecret, hex encoded.
+func (c *LimiterConfig) HashToken(token string) string {
+	mac := hmac.New(sha256.New, []byte(c.TokenHashSecret))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GetStorageKey returns the key given to the storage adapter. Keys other than IPs are hashed once TokenHashSecret is set,
+// so operators can use it to look up the state of a token.
+func (c *LimiterConfig) GetStorageKey(keyType string, key string) string {
+	if c.TokenHashSecret == "" || keyType == keyTypeIP || key == "" {
+		return key
+	}
+	return c.HashToken(key)
+}
+
+// GetBlock returns when the block of a key ends, or nil when it is not blocked.
+func (c *LimiterConfig) GetBlock(ctx context.Context, keyType string, key string) (*time.Time, error) {
+	return c.StorageAdapter.GetBlock(ctx, keyType, c.GetStorageKey(keyType, key))
+}
+
+// GetRedactedKey returns the key shown in decisions, errors and logs, which is never a raw token, even when storage keys
+// are not hashed.
+func (c *LimiterConfig) GetRedactedKey(keyType string, key string) string {
+	if keyType == keyTypeIP || key == "" {
+		return key
+	}
+	return c.HashToken(key)
+}
+
+func (c *LimiterConfig) redactTokens(tokens []string) []string {
+	if tokens == nil {
+		return nil
+	}
+	redacted := []string{}
+	for _, token := range tokens {
+		redacted = append(redacted, c.HashToken(token))
+	}
+	return redacted
+}
+
+// redacted returns a copy of the configuration that is safe to log, with every token hashed.
+func (c *LimiterConfig) redacted() *LimiterConfig {
+	redacted := *c
+	redacted.AllowedTokens = c.redactTokens(c.AllowedTokens)
+	redacted.DeniedTokens = c.redactTokens(c.DeniedTokens)
+	if c.CustomTokens != nil {
+		customTokens := map[string]*RateConfig{}
+		for token, rateConfig := range *c.CustomTokens {
+			customTokens[c.HashToken(token)] = rateConfig
+		}
+		redacted.CustomTokens = &customTokens
+	}
+	return &redacted
+}
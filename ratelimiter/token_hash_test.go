@@ -0,0 +1,90 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/danielzinhors/rate-limiter/ratelimiter/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TokenHashTestSuite struct {
+	suite.Suite
+}
+
+func TestTokenHashTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenHashTestSuite))
+}
+
+func (s *TokenHashTestSuite) TestHashToken() {
+	config := &LimiterConfig{TokenHashSecret: "secret"}
+	assert.Equal(s.T(), "9946dad4e00e913fc8be8e5d3f7e110a4a9e832f83fb09c345285d78638d8a0e", config.HashToken("abc"))
+	assert.NotEqual(s.T(), config.HashToken("abc"), (&LimiterConfig{TokenHashSecret: "other"}).HashToken("abc"))
+}
+
+func (s *TokenHashTestSuite) TestGetStorageKey() {
+	config := &LimiterConfig{TokenHashSecret: "secret"}
+	assert.Equal(s.T(), config.HashToken("abc"), config.GetStorageKey("TOKEN", "abc"))
+	assert.Equal(s.T(), config.HashToken("acme"), config.GetStorageKey("TENANT", "acme"))
+	assert.Equal(s.T(), "127.0.0.1", config.GetStorageKey("IP", "127.0.0.1"))
+	assert.Equal(s.T(), "", config.GetStorageKey("TOKEN", ""))
+	assert.Equal(s.T(), "abc", (&LimiterConfig{}).GetStorageKey("TOKEN", "abc"))
+}
+
+func (s *TokenHashTestSuite) TestGetRedactedKey() {
+	config := &LimiterConfig{}
+	assert.Equal(s.T(), config.HashToken("abc"), config.GetRedactedKey("TOKEN", "abc"))
+	assert.Equal(s.T(), config.HashToken("acme"), config.GetRedactedKey("RULE", "acme"))
+	assert.Equal(s.T(), "127.0.0.1", config.GetRedactedKey("IP", "127.0.0.1"))
+	assert.Equal(s.T(), "", config.GetRedactedKey("TOKEN", ""))
+}
+
+func (s *TokenHashTestSuite) TestGetBlock() {
+	controller := gomock.NewController(s.T())
+	storageAdapterMock := mocks.NewMockRateLimitStorageAdapter(controller)
+	config := &LimiterConfig{TokenHashSecret: "secret", StorageAdapter: storageAdapterMock}
+	block := time.Now().Add(time.Second)
+
+	storageAdapterMock.EXPECT().GetBlock(gomock.Any(), "TOKEN", config.HashToken("abc")).Return(&block, nil)
+
+	returnedBlock, err := config.GetBlock(context.Background(), "TOKEN", "abc")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), block, *returnedBlock)
+}
+
+func (s *TokenHashTestSuite) TestRedacted() {
+	config := &LimiterConfig{
+		CustomTokens:  &map[string]*RateConfig{"abc": {MaxRequestsPerSecond: 10}},
+		AllowedTokens: []string{"health"},
+		DeniedTokens:  []string{"revoked"},
+	}
+
+	jsonConfiguration, err := json.Marshal(config.redacted())
+	assert.Nil(s.T(), err)
+	assert.NotContains(s.T(), string(jsonConfiguration), "\"abc\"")
+	assert.NotContains(s.T(), string(jsonConfiguration), "health")
+	assert.NotContains(s.T(), string(jsonConfiguration), "revoked")
+	assert.Contains(s.T(), string(jsonConfiguration), config.HashToken("abc"))
+	assert.Contains(s.T(), *config.CustomTokens, "abc")
+	assert.Equal(s.T(), []string{"health"}, config.AllowedTokens)
+}
+
+func (s *TokenHashTestSuite) TestRedacted_EnvTokensInDebug() {
+	os.Setenv("RATE_LIMITER_TOKEN_s3cr3ttoken_MAX_REQUESTS", "5")
+	defer os.Unsetenv("RATE_LIMITER_TOKEN_s3cr3ttoken_MAX_REQUESTS")
+
+	var config *LimiterConfig
+	output, _ := captureOutput(func() error {
+		config = SetConfiguration(&LimiterConfig{Debug: true})
+		return nil
+	})
+
+	assert.Equal(s.T(), int64(5), (*config.CustomTokens)["s3cr3ttoken"].MaxRequestsPerSecond)
+	assert.NotContains(s.T(), output, "s3cr3ttoken")
+	assert.Contains(s.T(), output, "RATE_LIMITER_TOKEN_"+config.HashToken("s3cr3ttoken")+"_BLOCK_TIME")
+}
@@ -0,0 +1,84 @@
+package ratelimiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TokenSourceTestSuite struct {
+	suite.Suite
+}
+
+func TestTokenSourceTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenSourceTestSuite))
+}
+
+func (s *TokenSourceTestSuite) TestExtract() {
+	request := httptest.NewRequest("GET", "http://testing?api_key=query-token", nil)
+	request.Header.Set("X-API-Key", "header-token")
+	request.Header.Set("Authorization", "bearer bearer-token")
+	request.AddCookie(&http.Cookie{Name: "api_key", Value: "cookie-token"})
+
+	assert.Equal(s.T(), "header-token", (&TokenSource{Type: TokenSourceHeader, Name: "X-API-Key"}).Extract(request))
+	assert.Equal(s.T(), "bearer-token", (&TokenSource{Type: TokenSourceBearer}).Extract(request))
+	assert.Equal(s.T(), "query-token", (&TokenSource{Type: TokenSourceQuery, Name: "api_key"}).Extract(request))
+	assert.Equal(s.T(), "cookie-token", (&TokenSource{Type: TokenSourceCookie, Name: "api_key"}).Extract(request))
+	assert.Equal(s.T(), "", (&TokenSource{Type: TokenSourceCookie, Name: "missing"}).Extract(request))
+}
+
+func (s *TokenSourceTestSuite) TestExtract_BearerOtherScheme() {
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+
+	assert.Equal(s.T(), "", (&TokenSource{Type: TokenSourceBearer}).Extract(request))
+}
+
+func (s *TokenSourceTestSuite) TestGetToken_Order() {
+	config := &LimiterConfig{
+		TokenSources: []*TokenSource{
+			{Type: TokenSourceHeader, Name: "X-API-Key"},
+			{Type: TokenSourceQuery, Name: "api_key"},
+		},
+	}
+
+	request := httptest.NewRequest("GET", "http://testing?api_key=query-token", nil)
+	assert.Equal(s.T(), "query-token", config.GetToken(request))
+
+	request.Header.Set("X-API-Key", "header-token")
+	assert.Equal(s.T(), "header-token", config.GetToken(request))
+}
+
+func (s *TokenSourceTestSuite) TestGetToken_Default() {
+	request := httptest.NewRequest("GET", "http://testing", nil)
+	request.Header.Set("API_KEY", "abc")
+
+	assert.Equal(s.T(), "abc", (&LimiterConfig{}).GetToken(request))
+}
+
+func (s *TokenSourceTestSuite) TestIsKnownToken() {
+	config := &LimiterConfig{
+		CustomTokens: &map[string]*RateConfig{"abc": {MaxRequestsPerSecond: 10}},
+		TokenRegistry: TokenRegistryFunc(func(token string) bool {
+			return token == "def"
+		}),
+	}
+
+	assert.True(s.T(), config.IsKnownToken("abc"))
+	assert.True(s.T(), config.IsKnownToken("def"))
+	assert.False(s.T(), config.IsKnownToken("random"))
+	assert.False(s.T(), (&LimiterConfig{}).IsKnownToken("abc"))
+}
+
+func (s *TokenSourceTestSuite) TestIsUnknownToken() {
+	config := &LimiterConfig{CustomTokens: &map[string]*RateConfig{"abc": {MaxRequestsPerSecond: 10}}}
+	assert.Equal(s.T(), UnknownTokenPolicyAllow, config.GetUnknownTokenPolicy())
+	assert.False(s.T(), config.IsUnknownToken("random"))
+
+	config.UnknownTokenPolicy = UnknownTokenPolicyIP
+	assert.True(s.T(), config.IsUnknownToken("random"))
+	assert.False(s.T(), config.IsUnknownToken("abc"))
+}